@@ -2,19 +2,26 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"portal/internal/config"
 	addCartItem "portal/internal/http-server/handlers/add_cart_item"
+	apiClient "portal/internal/http-server/handlers/api_client"
+	apiClients "portal/internal/http-server/handlers/api_clients"
 	approveComment "portal/internal/http-server/handlers/approve_comment"
 	"portal/internal/http-server/handlers/article"
 	"portal/internal/http-server/handlers/articles"
+	blobGCHandler "portal/internal/http-server/handlers/blob_gc"
+	blobGCReport "portal/internal/http-server/handlers/blob_gc_report"
 	cartData "portal/internal/http-server/handlers/cart_data"
 	checkComments "portal/internal/http-server/handlers/check_comments"
 	"portal/internal/http-server/handlers/comment"
 	createPost "portal/internal/http-server/handlers/create_post"
+	deleteAPIClient "portal/internal/http-server/handlers/delete_api_client"
 	deleteComment "portal/internal/http-server/handlers/delete_comment"
 	deleteItem "portal/internal/http-server/handlers/delete_item"
 	deletePost "portal/internal/http-server/handlers/delete_post"
@@ -23,8 +30,13 @@ import (
 	dropCartItem "portal/internal/http-server/handlers/drop_cart_item"
 	editComment "portal/internal/http-server/handlers/edit_comment"
 	editPost "portal/internal/http-server/handlers/edit_post"
+	editProfile "portal/internal/http-server/handlers/edit_profile"
 	editTag "portal/internal/http-server/handlers/edit_tag"
+	editUserRole "portal/internal/http-server/handlers/edit_user_role"
+	editUserRoles "portal/internal/http-server/handlers/edit_user_roles"
 	"portal/internal/http-server/handlers/image"
+	ldapSyncHandler "portal/internal/http-server/handlers/ldap_sync"
+	ldapSyncReport "portal/internal/http-server/handlers/ldap_sync_report"
 	"portal/internal/http-server/handlers/like"
 	lockerReservation "portal/internal/http-server/handlers/locker_reservation"
 	lockerReservationDrop "portal/internal/http-server/handlers/locker_reservation_drop"
@@ -32,28 +44,54 @@ import (
 	lockerReservationUpdate "portal/internal/http-server/handlers/locker_reservation_update"
 	"portal/internal/http-server/handlers/me"
 	"portal/internal/http-server/handlers/order"
+	orgChart "portal/internal/http-server/handlers/org_chart"
+	orgChartChain "portal/internal/http-server/handlers/org_chart_chain"
+	orgChartReports "portal/internal/http-server/handlers/org_chart_reports"
 	phoneBook "portal/internal/http-server/handlers/phone_book"
+	phoneBookExport "portal/internal/http-server/handlers/phone_book_export"
+	postImage "portal/internal/http-server/handlers/post_image"
+	postImageDelete "portal/internal/http-server/handlers/post_image_delete"
+	postImageEdit "portal/internal/http-server/handlers/post_image_edit"
+	postImageFinalize "portal/internal/http-server/handlers/post_image_finalize"
+	postImageUploadURL "portal/internal/http-server/handlers/post_image_upload_url"
 	profile "portal/internal/http-server/handlers/profile"
+	profileAvatar "portal/internal/http-server/handlers/profile_avatar"
+	profileAvatarDelete "portal/internal/http-server/handlers/profile_avatar_delete"
+	profileAvatarImport "portal/internal/http-server/handlers/profile_avatar_import"
+	profileOverride "portal/internal/http-server/handlers/profile_override"
 	reservationHandler "portal/internal/http-server/handlers/reservation"
 	reservationDelete "portal/internal/http-server/handlers/reservation_delete"
 	reservationDrop "portal/internal/http-server/handlers/reservation_drop"
 	reservationEdit "portal/internal/http-server/handlers/reservation_edit"
 	reservationList "portal/internal/http-server/handlers/reservation_list"
 	reservationUpdate "portal/internal/http-server/handlers/reservation_update"
+	revokeUserSessions "portal/internal/http-server/handlers/revoke_user_sessions"
+	roleAudit "portal/internal/http-server/handlers/role_audit"
+	roleList "portal/internal/http-server/handlers/role_list"
+	sessionRevoke "portal/internal/http-server/handlers/session_revoke"
+	"portal/internal/http-server/handlers/sessions"
 	shopList "portal/internal/http-server/handlers/shop_list"
 	"portal/internal/http-server/handlers/tag"
 	tags "portal/internal/http-server/handlers/tags"
 	updateCartItem "portal/internal/http-server/handlers/update_cart_item"
 	userLockerReservations "portal/internal/http-server/handlers/user_locker_reservations"
 	userReservations "portal/internal/http-server/handlers/user_reservations"
+	userRoles "portal/internal/http-server/handlers/user_roles"
+	viewsHistory "portal/internal/http-server/handlers/views_history"
 	vc "portal/internal/lib/views_counter"
 
+	blobGC "portal/internal/lib/blob_gc"
+	ldapSync "portal/internal/lib/ldap_sync"
 	setupLogger "portal/internal/lib/logger/setup_logger"
 	"portal/internal/lib/logger/sl"
 	"portal/internal/lib/oauth"
+	"portal/internal/lib/rbac"
+	"portal/internal/storage/blob"
 	ldapServer "portal/internal/storage/ldap"
+	localStorage "portal/internal/storage/local"
 	minioServer "portal/internal/storage/minio"
 	"portal/internal/storage/postgres"
+	"portal/internal/structs/permissions"
 	"syscall"
 	"time"
 
@@ -61,6 +99,14 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// Хранилища изображений, выбираются параметром BlobStore.Backend
+const (
+	blobBackendMinIO = "minio"
+	blobBackendLocal = "local"
+)
+
+const defaultViewsFlushInterval = time.Minute
+
 func main() {
 	cfg := config.MustLoad()
 
@@ -78,6 +124,23 @@ func main() {
 		log.Info("storage closed")
 	}()
 
+	// portal migrate up|down|status - управление схемой БД без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(log, storage, os.Args[2:]); err != nil {
+			log.Error("migrate failed", sl.Err(err))
+			storage.DB.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Сервер не применяет миграции сам, только предупреждает о непримененных
+	if pending, err := pendingMigrations(context.Background(), storage); err != nil {
+		log.Error("failed to check schema migrations", sl.Err(err))
+	} else if pending > 0 {
+		log.Warn("database schema is outdated, run portal migrate up", slog.Int("pending", pending))
+	}
+
 	// Подключение и закрытие базы MSSQL 1C
 	/*storage1C, err := mssql.New(cfg.SQL)
 	if err != nil {
@@ -90,36 +153,106 @@ func main() {
 	}()*/
 
 	// Подключение и закрытие LDAP сервера
-	ldapsrv, err := ldapServer.New(cfg.LDAPServer.FQDN, cfg.LDAPServer.BaseDN, cfg.LDAPServer.UserAccountControl)
+	ldapsrv, err := ldapServer.New(cfg.LDAPServer.FQDN, cfg.LDAPServer.BaseDN, cfg.LDAPServer.UserAccountControl, ldapServer.ConnConfig{
+		Security:           cfg.LDAPServer.Security,
+		Port:               cfg.LDAPServer.Port,
+		CAPath:             cfg.LDAPServer.CAPath,
+		InsecureSkipVerify: cfg.LDAPServer.InsecureSkipVerify,
+		PoolSize:           cfg.LDAPServer.PoolSize,
+		Timeout:            cfg.LDAPServer.Timeout,
+	})
 	if err != nil {
 		log.Error("failed to init LDAP server", sl.Err(err))
 		os.Exit(1)
 	}
 	defer func() {
-		ldapsrv.LDAPConn.Close()
+		ldapsrv.Close()
 		log.Info("LDAP server closed")
 	}()
 
-	// Создание и подключение MinIO сервера
-	miniosrv, err := minioServer.New(cfg.MinIOServer.URL, cfg.MinIOServer.Bucket, false)
+	// Хранилище изображений: MinIO или локальный каталог
+	blobStore, err := newBlobStore(cfg)
 	if err != nil {
-		log.Error("failed to init MinIO provider", sl.Err(err))
+		log.Error("failed to init blob store", sl.Err(err))
 		os.Exit(1)
 	}
-	err = miniosrv.Connect()
+	log.Info("blob store initialized", slog.String("backend", cfg.BlobStore.Backend))
+
+	// Ключи подписи JWT. Предыдущие ключи используются только для проверки ранее выданных токенов
+	jwtCfg := cfg.BearerServer.JWT
+	signingKey, err := oauth.LoadJWTKey(jwtCfg.KeyID, jwtCfg.Algorithm, jwtCfg.PrivateKey, jwtCfg.PublicKey, []byte(cfg.BearerServer.Secret))
 	if err != nil {
-		log.Error("failed to connect to MinIO server", sl.Err(err))
+		log.Error("failed to load JWT signing key", sl.Err(err))
 		os.Exit(1)
 	}
+	var previousKeys []oauth.JWTKey
+	for _, k := range jwtCfg.PreviousKeys {
+		key, err := oauth.LoadJWTKey(k.KeyID, k.Algorithm, "", k.PublicKey, []byte(k.Secret))
+		if err != nil {
+			log.Error("failed to load previous JWT key", sl.Err(err))
+			os.Exit(1)
+		}
+		previousKeys = append(previousKeys, key)
+	}
+	tokenFormatter := oauth.NewJWTTokenSecurityProvider(jwtCfg.Issuer, signingKey, previousKeys...)
 
 	bearerServer := oauth.NewBearerServer(
 		cfg.BearerServer.Secret,
 		cfg.BearerServer.TokenTTL,
-		&oauth.UserVerifier{Storage: storage, LDAPServer: ldapsrv, Log: log},
-		nil)
+		&oauth.UserVerifier{Storage: storage, LDAPServer: ldapsrv, Log: log, TokenTTL: cfg.BearerServer.TokenTTL, RoleMapping: ldapRoleMapping(cfg.LDAPServer)},
+		tokenFormatter)
+	bearerServer.Cookie.SameSite = oauth.ParseSameSite(cfg.BearerServer.Cookie.SameSite)
+	bearerServer.Cookie.Path = cfg.BearerServer.Cookie.Path
+	bearerServer.Cookie.Domain = cfg.BearerServer.Cookie.Domain
+	// Без явного значения в конфиге cookie передаются только по https
+	if cfg.BearerServer.Cookie.Secure != nil {
+		bearerServer.Cookie.Secure = *cfg.BearerServer.Cookie.Secure
+	}
+	if cfg.BearerServer.RefreshTokenTTL > 0 {
+		bearerServer.RefreshTokenTTL = cfg.BearerServer.RefreshTokenTTL
+	}
+	if cfg.BearerServer.RefreshGracePeriod > 0 {
+		bearerServer.RefreshGracePeriod = cfg.BearerServer.RefreshGracePeriod
+	}
 
 	viewsCounter := vc.ViewsCounter{}
 
+	// Периодическое сохранение просмотров, чтобы при падении сервера терялись только последние
+	viewsFlushInterval := cfg.ViewsFlushInterval
+	if viewsFlushInterval <= 0 {
+		viewsFlushInterval = defaultViewsFlushInterval
+	}
+
+	// Периодическая синхронизация пользователей с AD
+	syncer := &ldapSync.Syncer{
+		Storage:     storage,
+		LDAPServer:  ldapsrv,
+		Log:         log,
+		DefaultRole: ldapRoleMapping(cfg.LDAPServer).DefaultRole,
+		PagingSize:  cfg.LDAPServer.SyncPagingSize,
+	}
+	if syncer.DefaultRole == 0 {
+		syncer.DefaultRole = oauth.DefaultLDAPRoleMapping().DefaultRole
+	}
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+	if cfg.LDAPServer.SyncInterval > 0 {
+		go syncer.Run(syncCtx, cfg.LDAPServer.SyncInterval)
+	}
+
+	// Периодическая очистка хранилища от файлов, на которые не ссылается БД
+	collector := &blobGC.Collector{
+		Storage:     storage,
+		BlobStore:   blobStore,
+		Log:         log,
+		GracePeriod: cfg.BlobStore.GCGracePeriod,
+	}
+	if cfg.BlobStore.GCInterval > 0 {
+		go collector.Run(syncCtx, cfg.BlobStore.GCInterval, cfg.BlobStore.GCDryRun)
+	}
+
+	go viewsCounter.Run(syncCtx, storage, log, viewsFlushInterval)
+
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID) // Добавляет request_id в каждый запрос, для трейсинга
@@ -127,19 +260,25 @@ func main() {
 	router.Use(middleware.Recoverer) // Если где-то внутри сервера (обработчика запроса) произойдет паника, приложение не должно упасть
 	router.Use(middleware.URLFormat) // Парсер URLов поступающих запросов
 
-	routeAPI(router, log, bearerServer, cfg.BearerServer.Secret, storage, miniosrv, &viewsCounter)
+	routeAPI(router, log, bearerServer, cfg.BearerServer.Secret, tokenFormatter, storage, blobStore, cfg.MinIOServer.PresignDownloads, &viewsCounter, syncCtx, syncer, collector, ldapsrv)
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
+	// Контекст всех запросов. Отменяется, если запросы не завершились за время остановки сервера,
+	// вместе с ним отменяются и запросы к БД
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	srv := &http.Server{
 		Addr:         cfg.Address,
 		Handler:      router,
 		ReadTimeout:  cfg.HTTPServer.Timeout,
 		WriteTimeout: cfg.HTTPServer.Timeout,
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return requestsCtx },
 	}
 
 	go func() {
@@ -154,83 +293,187 @@ func main() {
 	<-done
 	log.Info("stopping server")
 
-	// Обновляем значение просмотров всего в базе данных
-	err = storage.IncreaseViews(viewsCounter.Count())
-	if err != nil {
-		log.Error("failed to update total views in postgres: " + err.Error())
-	} else {
-		log.Info("total views updates")
-	}
+	stopSync()
+	// Дожидаемся синхронизации с AD: после отмены контекста она останавливается на очередном запросе к БД,
+	// а не обрывается вместе с процессом
+	syncer.Wait()
 
 	// TODO: move timeout to config
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
+		cancelRequests()
 		log.Error("failed to stop server")
-		return
+	} else {
+		log.Info("server stopped")
 	}
 
-	log.Info("server stopped")
+	// Сохраняем просмотры после остановки сервера, чтобы учесть и запросы, завершенные во время остановки
+	if err := viewsCounter.Flush(context.Background(), storage); err != nil {
+		log.Error("failed to flush views", sl.Err(err))
+	} else {
+		log.Info("views flushed")
+	}
 }
 
-func routeAPI(router *chi.Mux, log *slog.Logger, bearerServer *oauth.BearerServer, secret string, storage *postgres.Storage, miniosrv *minioServer.MinioProvider, viewsCounter *vc.ViewsCounter) {
+// newBlobStore creates images storage by cfg.BlobStore.Backend: "minio" (default) or "local"
+func newBlobStore(cfg *config.Config) (blob.BlobStore, error) {
+	switch cfg.BlobStore.Backend {
+	case "", blobBackendMinIO:
+		miniosrv, err := minioServer.New(cfg.MinIOServer.URL, cfg.MinIOServer.Bucket, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := miniosrv.Connect(); err != nil {
+			return nil, err
+		}
+		miniosrv.PresignTTL = cfg.MinIOServer.PresignTTL
+		return miniosrv, nil
+	case blobBackendLocal:
+		return localStorage.New(cfg.BlobStore.LocalDir)
+	default:
+		return nil, fmt.Errorf("unknown blob store backend %q", cfg.BlobStore.Backend)
+	}
+}
+
+// ldapRoleMapping converts LDAP groups to roles mapping from config. Empty mapping means oauth.DefaultLDAPRoleMapping
+func ldapRoleMapping(cfg config.LDAPServer) oauth.LDAPRoleMapping {
+	mapping := oauth.LDAPRoleMapping{DefaultRole: cfg.DefaultRole}
+	for _, g := range cfg.RoleMapping {
+		mapping.Groups = append(mapping.Groups, oauth.LDAPGroupRole{Group: g.Group, Role: g.Role})
+	}
+	for _, g := range cfg.DenyMapping {
+		mapping.Deny = append(mapping.Deny, oauth.LDAPGroupDeny{Group: g.Group, Permissions: g.Permissions})
+	}
+	return mapping
+}
+
+func routeAPI(router *chi.Mux, log *slog.Logger, bearerServer *oauth.BearerServer, secret string, tokenFormatter oauth.TokenSecureFormatter, storage *postgres.Storage, blobStore blob.BlobStore, presignImages bool, viewsCounter *vc.ViewsCounter, syncCtx context.Context, syncer *ldapSync.Syncer, collector *blobGC.Collector, ldapsrv *ldapServer.LDAPServer) {
+	// Проверка прав доступа ролей пользователя
+	authz := rbac.New(log, storage)
+	// Маршруты текущего пользователя недоступны сервисным клиентам: в их токенах нет user_id
+	userOnly := oauth.RequireUser(log)
+
 	//Secured API group
 	router.Group(func(r chi.Router) {
 		// use the Bearer Authentication middleware
-		r.Use(oauth.Authorize(secret, nil, bearerServer, log))
-		r.Post("/api/reservation", reservationHandler.New(log, storage))
+		r.Use(oauth.Authorize(secret, tokenFormatter, bearerServer, log))
+		// Проверка csrf токена для изменяющих запросов, авторизованных по cookie
+		r.Use(oauth.CSRF(log))
+		r.With(userOnly, authz.RequirePermission(permissions.ReservationCreate)).Post("/api/reservation", reservationHandler.New(log, storage))
 		r.Get("/api/reservation_list", reservationList.New(log, storage))
-		r.Get("/api/user_reservations", userReservations.New(log, storage))
-		r.Post("/api/reservation_update", reservationUpdate.New(log, storage))
-		r.Post("/api/reservation_drop", reservationDrop.New(log, storage))
+		r.With(userOnly, authz.RequirePermission(permissions.ReservationCreate)).Get("/api/user_reservations", userReservations.New(log, storage))
+		r.With(authz.RequirePermission(permissions.ReservationCreate)).Post("/api/reservation_update", reservationUpdate.New(log, storage))
+		r.With(authz.RequirePermission(permissions.ReservationCreate)).Post("/api/reservation_drop", reservationDrop.New(log, storage))
 
-		r.Post("/api/reservation_delete", reservationDelete.New(log, storage))
-		r.Post("/api/reservation_edit", reservationEdit.New(log, storage))
+		r.With(authz.RequirePermission(permissions.ReservationAdmin)).Post("/api/reservation_delete", reservationDelete.New(log, storage))
+		r.With(authz.RequirePermission(permissions.ReservationAdmin)).Post("/api/reservation_edit", reservationEdit.New(log, storage))
 
-		r.Post("/api/locker_reservation", lockerReservation.New(log, storage))
+		r.With(userOnly).Post("/api/locker_reservation", lockerReservation.New(log, storage))
 		r.Get("/api/locker_reservation_list", lockerReservationList.New(log, storage))
-		r.Get("/api/user_locker_reservations", userLockerReservations.New(log, storage))
+		r.With(userOnly).Get("/api/user_locker_reservations", userLockerReservations.New(log, storage))
 		r.Post("/api/locker_reservation_update", lockerReservationUpdate.New(log, storage))
 		r.Post("/api/locker_reservation_drop", lockerReservationDrop.New(log, storage))
 
-		r.Get("/api/profile", profile.New(log, storage))
-		r.Get("/api/me", me.New(log, storage))
+		r.With(userOnly).Get("/api/profile", profile.New(log, storage))
+		r.With(userOnly).Post("/api/profile", editProfile.New(log, storage))
+		r.With(authz.RequirePermission(permissions.UserManage)).Post("/api/profile/override", profileOverride.New(log, storage))
+		r.With(userOnly).Post("/api/profile/avatar", profileAvatar.New(log, storage, blobStore))
+		r.With(userOnly).Post("/api/profile/avatar_delete", profileAvatarDelete.New(log, storage, blobStore))
+		r.With(userOnly).Post("/api/profile/avatar_import", profileAvatarImport.New(log, storage, blobStore, ldapsrv))
+		r.With(userOnly).Get("/api/me", me.New(log, storage))
 		r.Get("/api/phone_book", phoneBook.New(log, storage))
+		r.Get("/api/phone_book/export", phoneBookExport.New(log, storage))
+
+		r.Get("/api/org_chart", orgChart.New(log, storage))
+		r.Get("/api/org_chart/reports", orgChartReports.New(log, storage))
+		r.Get("/api/org_chart/chain", orgChartChain.New(log, storage))
+
+		r.With(userOnly).Get("/api/sessions", sessions.New(log, storage))
+		r.With(userOnly).Post("/api/session_revoke", sessionRevoke.New(log, storage))
+		r.With(authz.RequirePermission(permissions.SessionAdmin)).Post("/api/revoke_user_sessions", revokeUserSessions.New(log, storage))
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequirePermission(permissions.ClientManage))
+			r.Post("/api/api_client", apiClient.New(log, storage))
+			r.Get("/api/api_clients", apiClients.New(log, storage))
+			r.Post("/api/delete_api_client", deleteAPIClient.New(log, storage))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequirePermission(permissions.RoleManage))
+			r.Get("/api/roles", roleList.New(log, storage))
+			r.Get("/api/user_roles", userRoles.New(log, storage))
+			r.Post("/api/edit_user_roles", editUserRoles.New(log, storage))
+			r.Post("/api/edit_user_role", editUserRole.New(log, storage))
+			r.Get("/api/role_audit", roleAudit.New(log, storage))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequirePermission(permissions.UserSync))
+			r.Post("/api/ldap_sync", ldapSyncHandler.New(syncCtx, log, syncer))
+			r.Get("/api/ldap_sync_report", ldapSyncReport.New(log, syncer))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequirePermission(permissions.StorageManage))
+			r.Post("/api/blob_gc", blobGCHandler.New(log, collector))
+			r.Get("/api/blob_gc_report", blobGCReport.New(log, collector))
+		})
 
 		r.Get("/api/shop_list", shopList.New(log, storage))
-		r.Post("/api/add_cart_item", addCartItem.New(log, storage))
-		r.Post("/api/order", order.New(log, storage))
-		r.Get("/api/cart_data", cartData.New(log, storage))
-		r.Post("/api/drop_cart", dropCart.New(log, storage))
+		r.With(userOnly).Post("/api/add_cart_item", addCartItem.New(log, storage))
+		r.With(userOnly).Post("/api/order", order.New(log, storage))
+		r.With(userOnly).Get("/api/cart_data", cartData.New(log, storage))
+		r.With(userOnly).Post("/api/drop_cart", dropCart.New(log, storage))
 		r.Post("/api/drop_cart_item", dropCartItem.New(log, storage))
 		r.Post("/api/update_cart_item", updateCartItem.New(log, storage))
-		r.Post("/api/delete_item", deleteItem.New(log, storage))
+		r.With(authz.RequirePermission(permissions.ShopManage)).Post("/api/delete_item", deleteItem.New(log, storage))
 
-		r.Post("/api/comment", comment.New(log, storage))
+		r.With(userOnly).Post("/api/comment", comment.New(log, storage))
 		r.Post("/api/edit_comment", editComment.New(log, storage))
-		r.Get("/api/check_comments", checkComments.New(log, storage))
-		r.Post("/api/approve_comment", approveComment.New(log, storage))
-		r.Post("/api/delete_comment", deleteComment.New(log, storage))
-
-		r.Post("/api/like", like.New(log, storage))
-
-		r.Post("/api/create_article", createPost.New(log, storage, miniosrv))
-		r.Post("/api/edit_article", editPost.New(log, storage, miniosrv))
-		r.Post("/api/delete_article", deletePost.New(log, storage, miniosrv))
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequirePermission(permissions.CommentModerate))
+			r.Get("/api/check_comments", checkComments.New(log, storage))
+			r.Post("/api/approve_comment", approveComment.New(log, storage))
+			r.Post("/api/delete_comment", deleteComment.New(log, storage))
+		})
+
+		r.With(userOnly).Post("/api/like", like.New(log, storage))
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequirePermission(permissions.NewsWrite))
+			r.Post("/api/create_article", createPost.New(log, storage, blobStore))
+			r.Post("/api/edit_article", editPost.New(log, storage, blobStore))
+			r.Post("/api/delete_article", deletePost.New(log, storage, blobStore))
+			r.Post("/api/post_image", postImage.New(log, storage, blobStore))
+			r.Post("/api/post_image_edit", postImageEdit.New(log, storage))
+			r.Post("/api/post_image_delete", postImageDelete.New(log, storage, blobStore))
+			r.Post("/api/post_image_upload_url", postImageUploadURL.New(log, storage, blobStore))
+			r.Post("/api/post_image_finalize", postImageFinalize.New(log, storage, blobStore))
+
+			r.Post("/api/tag", tag.New(log, storage))
+			r.Post("/api/edit_tag", editTag.New(log, storage))
+			r.Post("/api/delete_tag", deleteTag.New(log, storage))
+		})
+	})
 
-		r.Post("/api/tag", tag.New(log, storage))
-		r.Post("/api/edit_tag", editTag.New(log, storage))
-		r.Post("/api/delete_tag", deleteTag.New(log, storage))
+	// Выход не требует действующего access токена, но изменяет состояние и проверяет csrf токен
+	router.Group(func(r chi.Router) {
+		r.Use(oauth.CSRF(log))
+		r.Post("/api/logout", bearerServer.Logout)
 	})
 
 	// Public API group
 	router.Group(func(r chi.Router) {
 		r.Post("/api/login", bearerServer.UserCredentials)
+		r.Post("/api/token", bearerServer.ClientCredentials)
 
 		r.Get("/api/articles", articles.New(log, storage, viewsCounter))
-		r.Get("/api/image", image.New(log, miniosrv))
+		r.Get("/api/image", image.New(log, blobStore, presignImages))
 		r.Get("/api/article", article.New(log, storage))
 		r.Get("/api/tags", tags.New(log, storage))
+		r.Get("/api/views_history", viewsHistory.New(log, storage))
 	})
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/migrations"
+)
+
+const migrateUsage = "usage: portal migrate up|down|status"
+
+// runMigrate handles "portal migrate" subcommands
+func runMigrate(log *slog.Logger, storage *postgres.Storage, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(ctx, storage)
+		for _, m := range applied {
+			log.Info("migration applied", slog.Int("version", m.Version), slog.String("name", m.Name))
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Info("schema is up to date")
+		}
+	case "down":
+		m, err := migrations.Down(ctx, storage)
+		if err != nil {
+			return err
+		}
+		log.Info("migration rolled back", slog.Int("version", m.Version), slog.String("name", m.Name))
+	case "status":
+		statuses, err := migrations.GetStatus(ctx, storage)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// pendingMigrations returns the number of migrations not applied yet
+func pendingMigrations(ctx context.Context, storage *postgres.Storage) (int, error) {
+	statuses, err := migrations.GetStatus(ctx, storage)
+	if err != nil {
+		return 0, err
+	}
+
+	var pending int
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
@@ -79,8 +79,13 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Запрос и проверка доступности item для заказа
 		var i shop.Item
-		if err := i.GetIsAvailable(storage, req.ItemID); err != nil {
+		if err := i.GetIsAvailable(r.Context(), storage.DB, req.ItemID); err != nil {
 			log.Error("failed to get item status", sl.Err(err))
+			if errors.Is(err, storageHandler.ErrItemDoesNotExist) {
+				w.WriteHeader(404)
+				render.JSON(w, r, resp.Error("item does not exist"))
+				return
+			}
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to get item status"))
 			return
@@ -92,39 +97,46 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			return
 		}
 
-		// Запрос cart_id для вызывающего user_id
-		var c shop.Cart
-		err = c.GetActiveCartID(storage, userID)
-		if err != nil {
-			// Если ошибка не об отсутствии корзины, то выход по стнадартной ошибке БД
-			if !errors.As(err, &storageHandler.ErrCartDoesNotExist) {
-				log.Error("failed to get active cart id", sl.Err(err))
-				w.WriteHeader(422)
-				render.JSON(w, r, resp.Error("failed to get active cart id"))
-				return
-			}
-			// Если ошибка выше была об отсутствии корзины, то создаем корзину
-			if err := c.NewCart(storage, userID); err != nil {
-				log.Error("failed to create new cart", sl.Err(err))
-				w.WriteHeader(422)
-				render.JSON(w, r, resp.Error("failed to create cart"))
-				return
+		// Создание корзины и добавление в нее item выполняются в одной транзакции, чтобы не оставалось пустых корзин
+		var errMsg string
+		err = storage.WithTx(r.Context(), func(tx postgres.Querier) error {
+			// Запрос cart_id для вызывающего user_id
+			var c shop.Cart
+			err := c.GetActiveCartID(r.Context(), tx, userID)
+			if err != nil {
+				// Если ошибка не об отсутствии корзины, то выход по стнадартной ошибке БД
+				if !errors.Is(err, storageHandler.ErrCartDoesNotExist) {
+					errMsg = "failed to get active cart id"
+					return err
+				}
+				// Если ошибка выше была об отсутствии корзины, то создаем корзину
+				if err := c.NewCart(r.Context(), tx, userID); err != nil {
+					errMsg = "failed to create cart"
+					return err
+				}
+				// Получаем номер созданной корзины
+				if err := c.GetActiveCartID(r.Context(), tx, userID); err != nil {
+					errMsg = "failed to get active cart id"
+					return err
+				}
 			}
-			// Получаем номер созданной корзины
-			if err := c.GetActiveCartID(storage, userID); err != nil {
-				log.Error("failed to get active cart id", sl.Err(err))
-				w.WriteHeader(422)
-				render.JSON(w, r, resp.Error("failed to get active cart id"))
-				return
+
+			// Добавление item в корзину
+			var ici shop.InCartItem
+			if err := ici.NewInCartItem(r.Context(), tx, req.ItemID, req.Quantity, c.CartID); err != nil {
+				errMsg = "failed to add item in cart"
+				return err
 			}
-		}
 
-		// Добавление item в корзину
-		var ici shop.InCartItem
-		if err := ici.NewInCartItem(storage, req.ItemID, req.Quantity, c.CartID); err != nil {
-			log.Error("failed to add item in cart", sl.Err(err))
+			return nil
+		})
+		if err != nil {
+			if errMsg == "" {
+				errMsg = "failed to add item in cart"
+			}
+			log.Error(errMsg, sl.Err(err))
 			w.WriteHeader(422)
-			render.JSON(w, r, resp.Error("failed to add item in cart"))
+			render.JSON(w, r, resp.Error(errMsg))
 			return
 		}
 
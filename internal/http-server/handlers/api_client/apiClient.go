@@ -0,0 +1,118 @@
+package apiClient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/client"
+	"portal/internal/structs/roles"
+	"slices"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	ClientID string `json:"client_id" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Scopes   []int  `json:"scopes" validate:"required"`
+}
+
+// Секрет возвращается только один раз при создании клиента, в БД хранится его хэш
+type Response struct {
+	resp.Response
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apiClient.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		// Сервисному клиенту нельзя выдать роль администратора
+		if slices.Contains(req.Scopes, roles.SuperAdmin) {
+			log.Error("super admin scope is not allowed for api client")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("super admin scope is not allowed for api client"))
+			return
+		}
+
+		secret, secretHash, err := oauth.GenerateClientSecret()
+		if err != nil {
+			log.Error("failed to generate client secret", sl.Err(err))
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("failed to generate client secret"))
+			return
+		}
+
+		var c client.APIClient
+		if err := c.NewClient(r.Context(), storage.DB, req.ClientID, req.Name, secretHash, req.Scopes); err != nil {
+			log.Error("failed to create api client", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to create api client"))
+			return
+		}
+
+		log.Info("api client successfully created", slog.String("client_id", req.ClientID))
+
+		responseOK(w, r, log, req.ClientID, secret)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, clientID, clientSecret string) {
+	response, err := json.Marshal(Response{
+		Response:     resp.OK(),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
@@ -0,0 +1,58 @@
+package apiClients
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/client"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Clients []client.APIClient `json:"clients"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apiClients.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var c client.APIClient
+		cs, err := c.GetClients(r.Context(), storage.DB)
+		if err != nil {
+			log.Error("failed to get api clients", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get api clients"))
+			return
+		}
+
+		log.Info("api clients gotten")
+
+		responseOK(w, r, log, cs)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, clients []client.APIClient) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Clients:  clients,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
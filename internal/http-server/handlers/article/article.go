@@ -71,7 +71,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Получаем текст поста в p по ID поста
 		var p news.Post
-		if err := p.GetText(storage, req.PostID); err != nil {
+		if err := p.GetText(r.Context(), storage.DB, req.PostID); err != nil {
 			log.Error("failed to get post text", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to get post text"))
@@ -80,7 +80,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Получаем все комментарии по post ID
 		var c news.Comment
-		cs, err := c.GetCommentsByPostID(storage, req.PostID)
+		cs, err := c.GetCommentsByPostID(r.Context(), storage.DB, req.PostID)
 		if err != nil {
 			log.Error("failed to get comments", sl.Err(err))
 			w.WriteHeader(422)
@@ -94,21 +94,21 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			ci := CommentInfo{Comment: c}
 			// Запрашиваем ФИО пользователя, оставившего комментарий к посту
 			var u user.User
-			err := u.GetUsername(storage, ci.UserID)
+			err := u.GetUsername(r.Context(), storage.DB, ci.UserID)
 			if err != nil {
 				log.Error("failed to get username", sl.Err(err))
 				w.WriteHeader(422)
 				render.JSON(w, r, resp.Error("failed to get username"))
 				return
 			}
-			err = u.GetUserInfo(storage, u.Username)
+			err = u.GetUserInfo(r.Context(), storage.DB, u.Username)
 			if err != nil {
 				log.Error("failed to get user info", sl.Err(err))
 				w.WriteHeader(422)
 				render.JSON(w, r, resp.Error("failed to get user info"))
 				return
 			}
-			err = u.GetImagePath(storage, ci.UserID)
+			err = u.GetImagePath(r.Context(), storage.DB, ci.UserID)
 			if err != nil {
 				log.Error("failed to get user info", sl.Err(err))
 				w.WriteHeader(422)
@@ -124,7 +124,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		}
 
 		// Добавляем просмотр посту в p по ID поста
-		if err := p.AddView(storage, req.PostID); err != nil {
+		if err := p.AddView(r.Context(), storage.DB, req.PostID); err != nil {
 			log.Error("failed to add post view", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to add post view"))
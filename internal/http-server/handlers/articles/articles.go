@@ -8,6 +8,7 @@ import (
 	vc "portal/internal/lib/views_counter"
 	storageHandler "portal/internal/storage"
 	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/counter"
 	"portal/internal/storage/postgres/entities/news"
 	"strconv"
 	"time"
@@ -38,11 +39,12 @@ type Request struct {
 // Запрашиваемая API структура
 type Article struct {
 	news.Post
-	LikesAmount    int        `json:"likes_amount"`
-	CommentsAmount int        `json:"comments_amount"`
-	IsLiked        bool       `json:"is_liked"`
-	Images         []string   `json:"images"`
-	Tags           []news.Tag `json:"tags"`
+	LikesAmount    int              `json:"likes_amount"`
+	CommentsAmount int              `json:"comments_amount"`
+	IsLiked        bool             `json:"is_liked"`
+	Images         []string         `json:"images"`
+	PostImages     []news.PostImage `json:"post_images"`
+	Tags           []news.Tag       `json:"tags"`
 }
 
 type Response struct {
@@ -111,7 +113,7 @@ func New(log *slog.Logger, storage *postgres.Storage, viewsCounter *vc.ViewsCoun
 
 		// Запрашиваем все посты из БД
 		var p news.Post
-		ps, err := p.GetPostsPage(storage, req.TagsID, req.Page, req.CreatedAfter, req.CreatedBefore)
+		ps, err := p.GetPostsPage(r.Context(), storage.DB, req.TagsID, req.Page, req.CreatedAfter, req.CreatedBefore)
 		// Случай когда указана страница вне диапазона
 		if errors.As(err, &(storageHandler.ErrPageInOutOfRange)) {
 			log.Error("failed to get catalog", sl.Err(err))
@@ -150,7 +152,7 @@ func New(log *slog.Logger, storage *postgres.Storage, viewsCounter *vc.ViewsCoun
 		for i := range articles {
 			// Запрос и запись количества лайков для поста
 			var l news.Like
-			likesAmount, err := l.GetLikesAmount(storage, articles[i].PostID)
+			likesAmount, err := l.GetLikesAmount(r.Context(), storage.DB, articles[i].PostID)
 			if err != nil {
 				log.Error("failed to get likes amount", sl.Err(err))
 				w.WriteHeader(422)
@@ -161,7 +163,7 @@ func New(log *slog.Logger, storage *postgres.Storage, viewsCounter *vc.ViewsCoun
 
 			isLiked := true
 			if req.UserID != 0 {
-				isLiked, err = l.IsLikedByUserID(storage, articles[i].PostID, req.UserID)
+				isLiked, err = l.IsLikedByUserID(r.Context(), storage.DB, articles[i].PostID, req.UserID)
 				if err != nil {
 					log.Error("failed to check post is liked by user", sl.Err(err))
 					w.WriteHeader(422)
@@ -173,7 +175,7 @@ func New(log *slog.Logger, storage *postgres.Storage, viewsCounter *vc.ViewsCoun
 
 			// Получаем кол-во комментариев по post ID
 			var c news.Comment
-			commentsAmount, err := c.GetCommentsAmount(storage, articles[i].PostID)
+			commentsAmount, err := c.GetCommentsAmount(r.Context(), storage.DB, articles[i].PostID)
 			if err != nil {
 				log.Error("failed to get comments amount", sl.Err(err))
 				w.WriteHeader(422)
@@ -184,18 +186,21 @@ func New(log *slog.Logger, storage *postgres.Storage, viewsCounter *vc.ViewsCoun
 
 			// Запрос и запись путей к изображениям для поста
 			var pi news.PostImage
-			paths, err := pi.GetImageInfoByPostID(storage, articles[i].PostID)
+			images, err := pi.GetPostImages(r.Context(), storage.DB, articles[i].PostID)
 			if err != nil {
 				log.Error("failed to get post image paths", sl.Err(err))
 				w.WriteHeader(422)
 				render.JSON(w, r, resp.Error("failed to get post image paths"))
 				return
 			}
-			articles[i].Images = paths
+			articles[i].PostImages = images
+			for _, image := range images {
+				articles[i].Images = append(articles[i].Images, image.Path)
+			}
 
 			// Запрос и запись тэгов для поста
 			var t news.Tag
-			tags, err := t.GetTagsByPostID(storage, articles[i].PostID)
+			tags, err := t.GetTagsByPostID(r.Context(), storage.DB, articles[i].PostID)
 			if err != nil {
 				log.Error("failed to get post tags", sl.Err(err))
 				w.WriteHeader(422)
@@ -207,18 +212,17 @@ func New(log *slog.Logger, storage *postgres.Storage, viewsCounter *vc.ViewsCoun
 
 		log.Info("articles successfully gotten")
 
-		// Читаем значение просмотров всего было при запуске сервера из БД
-		views, err := storage.GetViews()
-		if err != nil {
+		// Читаем сохраненное в БД значение просмотров всего
+		var sc counter.SiteCounter
+		if err := sc.GetCounter(r.Context(), storage.DB, counter.TotalViews); err != nil {
 			log.Error("failed to get views from postgres", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to get views from postgres"))
 			return
 		}
 
-		// Добавляем к значению просмотров всего было просмотры во время текущего сеасна работы сервера
-		curSessionViews := viewsCounter.Count()
-		totalViews := views + curSessionViews
+		// Добавляем к сохраненному значению просмотры, которые еще не сохранены в БД
+		totalViews := int(sc.N) + viewsCounter.Count()
 
 		responseOK(w, r, log, articles, totalViews)
 
@@ -0,0 +1,84 @@
+package blobGCHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	blobGC "portal/internal/lib/blob_gc"
+	"portal/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	DryRun *bool `json:"dry_run"`
+}
+
+type Response struct {
+	resp.Response
+	Report blobGC.Report `json:"report"`
+}
+
+func New(log *slog.Logger, collector *blobGC.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.blobGC.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Пустое тело допустимо: по умолчанию запускается dry run
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		dryRun := req.DryRun == nil || *req.DryRun
+
+		log.Info("request body decoded", slog.Bool("dry_run", dryRun))
+
+		// Ручной запуск очистки хранилища от файлов без ссылок в БД
+		report, err := collector.Collect(r.Context(), dryRun)
+		if err != nil {
+			if errors.Is(err, blobGC.ErrCollectInProgress) {
+				log.Error("blob gc is already in progress")
+				w.WriteHeader(409)
+				render.JSON(w, r, resp.Error("blob gc is already in progress"))
+				return
+			}
+			log.Error("failed to collect orphaned objects", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to collect orphaned objects"))
+			return
+		}
+
+		log.Info("orphaned objects successfully collected")
+
+		responseOK(w, r, log, report)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, report blobGC.Report) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Report:   report,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
@@ -0,0 +1,56 @@
+package blobGCReport
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	blobGC "portal/internal/lib/blob_gc"
+	"portal/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Report blobGC.Report `json:"report"`
+}
+
+func New(log *slog.Logger, collector *blobGC.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.blobGCReport.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		report, ok := collector.LastReport()
+		if !ok {
+			log.Error("no blob gc report yet")
+			w.WriteHeader(404)
+			render.JSON(w, r, resp.Error("no blob gc report yet"))
+			return
+		}
+
+		log.Info("blob gc report gotten")
+
+		responseOK(w, r, log, report)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, report blobGC.Report) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Report:   report,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
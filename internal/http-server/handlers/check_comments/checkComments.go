@@ -5,12 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"portal/internal/lib/logger/sl"
-	"portal/internal/lib/oauth"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/news"
 	"portal/internal/storage/postgres/entities/user"
-	"portal/internal/structs/roles"
-	"slices"
 
 	resp "portal/internal/lib/api/response"
 
@@ -39,29 +36,9 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем разрешенные роли
-		allowedRoles := []int{roles.NewsEditor, roles.SuperAdmin}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if !slices.Contains(allowedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		// Получаем все комментарии по post ID
 		var c news.Comment
-		cs, err := c.GetUncheckedComments(storage)
+		cs, err := c.GetUncheckedComments(r.Context(), storage.DB)
 		if err != nil {
 			log.Error("failed to get unchecked comments", sl.Err(err))
 			w.WriteHeader(422)
@@ -75,14 +52,14 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			ci := CommentInfo{Comment: c}
 			// Запрашиваем ФИО пользователя, оставившего комментарий к посту
 			var u user.User
-			err := u.GetUsername(storage, ci.UserID)
+			err := u.GetUsername(r.Context(), storage.DB, ci.UserID)
 			if err != nil {
 				log.Error("failed to get username", sl.Err(err))
 				w.WriteHeader(422)
 				render.JSON(w, r, resp.Error("failed to get username"))
 				return
 			}
-			err = u.GetUserInfo(storage, u.Username)
+			err = u.GetUserInfo(r.Context(), storage.DB, u.Username)
 			if err != nil {
 				log.Error("failed to get user info", sl.Err(err))
 				w.WriteHeader(422)
@@ -77,7 +77,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Обновляем значение текста комментария в БД
 		var c news.Comment
-		err = c.NewComment(storage, req.Text, userID, req.PostID)
+		err = c.NewComment(r.Context(), storage.DB, req.Text, userID, req.PostID)
 		if err != nil {
 			log.Error("failed to add comment", sl.Err(err))
 			w.WriteHeader(422)
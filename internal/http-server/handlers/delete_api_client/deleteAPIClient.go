@@ -0,0 +1,78 @@
+package deleteAPIClient
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/client"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	ClientID string `json:"client_id" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.deleteAPIClient.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		// Удаляем клиента. Уже выданные токены действуют до истечения TokenTTL
+		var c client.APIClient
+		if err := c.DeleteClient(r.Context(), storage.DB, req.ClientID); err != nil {
+			log.Error("failed to delete api client", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to delete api client"))
+			return
+		}
+
+		log.Info("api client successfully deleted", slog.String("client_id", req.ClientID))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
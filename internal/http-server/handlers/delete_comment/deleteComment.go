@@ -7,11 +7,8 @@ import (
 	"net/http"
 	resp "portal/internal/lib/api/response"
 	"portal/internal/lib/logger/sl"
-	"portal/internal/lib/oauth"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/news"
-	"portal/internal/structs/roles"
-	"slices"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
@@ -35,26 +32,6 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем разрешенные роли
-		allowedRoles := []int{roles.NewsEditor, roles.SuperAdmin}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if !slices.Contains(allowedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		var req Request
 
 		// Декодируем json запроса
@@ -87,7 +64,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Удаляем комментарий из БД
 		var c news.Comment
-		if err := c.DeleteComment(storage, req.CommentID); err != nil {
+		if err := c.DeleteComment(r.Context(), storage.DB, req.CommentID); err != nil {
 			log.Error("failed to delete comment", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to delete comment"))
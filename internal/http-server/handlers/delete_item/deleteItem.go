@@ -6,11 +6,8 @@ import (
 	"net/http"
 	resp "portal/internal/lib/api/response"
 	"portal/internal/lib/logger/sl"
-	"portal/internal/lib/oauth"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/shop"
-	"portal/internal/structs/roles"
-	"slices"
 
 	"log/slog"
 
@@ -36,26 +33,6 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем разрешенные роли
-		allowedRoles := []int{roles.ShopEditor, roles.SuperAdmin}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if !slices.Contains(allowedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		var req Request
 
 		// Декодируем json запроса
@@ -88,7 +65,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Удаляем предмет из БД
 		var i shop.Item
-		if err := i.DeleteItem(storage, req.ItemID); err != nil {
+		if err := i.DeleteItem(r.Context(), storage.DB, req.ItemID); err != nil {
 			log.Error("failed to delete item from shop", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to delete item from shop"))
@@ -7,12 +7,10 @@ import (
 	"net/http"
 	resp "portal/internal/lib/api/response"
 	"portal/internal/lib/logger/sl"
-	"portal/internal/lib/oauth"
-	minioServer "portal/internal/storage/minio"
+	postImage "portal/internal/lib/post_image"
+	"portal/internal/storage/blob"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/news"
-	"portal/internal/structs/roles"
-	"slices"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
@@ -27,7 +25,7 @@ type Response struct {
 	resp.Response
 }
 
-func New(log *slog.Logger, storage *postgres.Storage, miniosrv *minioServer.MinioProvider) http.HandlerFunc {
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.deleteArticle.New"
 
@@ -36,26 +34,6 @@ func New(log *slog.Logger, storage *postgres.Storage, miniosrv *minioServer.Mini
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем разрешенные роли
-		allowedRoles := []int{roles.NewsEditor, roles.SuperAdmin}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if !slices.Contains(allowedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		var req Request
 
 		// Декодируем json запроса
@@ -86,15 +64,34 @@ func New(log *slog.Logger, storage *postgres.Storage, miniosrv *minioServer.Mini
 			return
 		}
 
+		// Запоминаем имена фото поста до удаления, записи о них удалятся каскадно
+		var pi news.PostImage
+		imageNames, err := pi.GetObjectNamesByPostID(r.Context(), storage.DB, req.PostID)
+		if err != nil {
+			log.Error("failed to get post image names", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get post image names"))
+			return
+		}
+
 		// Удаляем новость из БД
 		var p news.Post
-		if err := p.DeletePost(storage, req.PostID); err != nil {
+		if err := p.DeletePost(r.Context(), storage.DB, req.PostID); err != nil {
 			log.Error("failed to delete post", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to delete post"))
 			return
 		}
 
+		// Удаляем фото из хранилища. Ошибка не отменяет удаление поста
+		for _, imageName := range imageNames {
+			for _, name := range postImage.ObjectNames(imageName) {
+				if err := blobStore.Delete(r.Context(), name); err != nil {
+					log.Error("failed to remove image from minio", slog.String("name", name), sl.Err(err))
+				}
+			}
+		}
+
 		log.Info("post successfully deleted")
 
 		render.JSON(w, r, resp.OK())
@@ -65,7 +65,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Удаление item из корзины
 		var ici *shop.InCartItem
-		if err := ici.DeleteInCartItem(storage, req.InCartItemID); err != nil {
+		if err := ici.DeleteInCartItem(r.Context(), storage.DB, req.InCartItemID); err != nil {
 			log.Error("failed to delete in cart item", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to delete in cart item"))
@@ -66,7 +66,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Обновляем значение текста комментария в БД
 		var c news.Comment
-		err = c.UpdateCommentText(storage, req.CommentID, req.Text)
+		err = c.UpdateCommentText(r.Context(), storage.DB, req.CommentID, req.Text)
 		if err != nil {
 			log.Error("failed to update comment text", sl.Err(err))
 			w.WriteHeader(422)
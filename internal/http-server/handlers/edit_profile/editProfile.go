@@ -0,0 +1,97 @@
+package editProfile
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Поля профиля, которые пользователь заполняет сам. Запрос заменяет все поля целиком
+type Request struct {
+	ExtraPhone   string   `json:"extra_phone" validate:"max=25"`
+	Office       string   `json:"office" validate:"max=50"`
+	Bio          string   `json:"bio" validate:"max=500"`
+	Birthday     string   `json:"birthday" validate:"omitempty,datetime=2006-01-02"`
+	ShowBirthday bool     `json:"show_birthday"`
+	Skills       []string `json:"skills" validate:"max=20,dive,required,max=50"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.editProfile.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		u := user.User{
+			ExtraPhone:   req.ExtraPhone,
+			Office:       req.Office,
+			Bio:          req.Bio,
+			Birthday:     req.Birthday,
+			ShowBirthday: req.ShowBirthday,
+			Skills:       req.Skills,
+		}
+		if err := u.UpdatePersonalInfo(r.Context(), storage.DB, userID); err != nil {
+			log.Error("failed to update personal info", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to update personal info"))
+			return
+		}
+
+		log.Info("profile successfully updated")
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -6,11 +6,8 @@ import (
 	"log/slog"
 	"net/http"
 	"portal/internal/lib/logger/sl"
-	"portal/internal/lib/oauth"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/news"
-	"portal/internal/structs/roles"
-	"slices"
 
 	resp "portal/internal/lib/api/response"
 
@@ -39,26 +36,6 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем разрешенные роли
-		allowedRoles := []int{roles.NewsEditor, roles.SuperAdmin}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if !slices.Contains(allowedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		var req Request
 
 		// Декодируем json запроса
@@ -99,7 +76,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Обновляем значение текста комментария в БД
 		var c news.Tag
-		err = c.UpdateTag(storage, req.TagID, req.Name, req.BackgroundColor, *req.TextColor)
+		err = c.UpdateTag(r.Context(), storage.DB, req.TagID, req.Name, req.BackgroundColor, *req.TextColor)
 		if err != nil {
 			log.Error("failed to update tag", sl.Err(err))
 			w.WriteHeader(422)
@@ -0,0 +1,92 @@
+package editUserRole
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/role"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Role - основная роль пользователя. Роль вне соответствия групп LDAP не меняется при входе пользователя
+type Request struct {
+	UserID int `json:"user_id" validate:"required"`
+	Role   int `json:"role" validate:"required"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.editUserRole.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем username администратора из токена авторизации для аудита
+		username := r.Context().Value(oauth.CredentialContext).(string)
+		if username == "" {
+			log.Error("no username in token")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no username in token"))
+			return
+		}
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		var ro role.Role
+		if _, err := ro.SetMainRole(r.Context(), storage, req.UserID, req.Role, username, role.SourceAdmin); err != nil {
+			if errors.Is(err, storageHandler.ErrUserIDDoesNotExist) {
+				log.Error("user does not exist", sl.Err(err))
+				w.WriteHeader(404)
+				render.JSON(w, r, resp.Error("user does not exist"))
+				return
+			}
+			log.Error("failed to set user role", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to set user role"))
+			return
+		}
+
+		log.Info("user role successfully set", slog.Int("user_id", req.UserID), slog.Int("role", req.Role))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,85 @@
+package editUserRoles
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/role"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Roles - дополнительные роли пользователя, основная роль из "user" сохраняется
+type Request struct {
+	UserID int   `json:"user_id" validate:"required"`
+	Roles  []int `json:"roles"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.editUserRoles.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем username администратора из токена авторизации для аудита
+		username := r.Context().Value(oauth.CredentialContext).(string)
+		if username == "" {
+			log.Error("no username in token")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no username in token"))
+			return
+		}
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		var ro role.Role
+		if err := ro.SetUserRoles(r.Context(), storage, req.UserID, req.Roles, username); err != nil {
+			log.Error("failed to set user roles", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to set user roles"))
+			return
+		}
+
+		log.Info("user roles successfully set", slog.Int("user_id", req.UserID), slog.Any("roles", req.Roles))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
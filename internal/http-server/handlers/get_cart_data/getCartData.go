@@ -43,7 +43,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Запрос cart_id для вызывающего user_id
 		var c shop.Cart
-		err := c.GetActiveCartID(storage, userID)
+		err := c.GetActiveCartID(r.Context(), storage.DB, userID)
 		if err != nil {
 			// Если ошибка не об отсутствии корзины, то выход по стнадартной ошибке БД
 			if !errors.As(err, &storageHandler.ErrCartDoesNotExist) {
@@ -53,14 +53,14 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 				return
 			}
 			// Если ошибка выше была об отсутствии корзины, то создаем корзину
-			if err := c.NewCart(storage, userID); err != nil {
+			if err := c.NewCart(r.Context(), storage.DB, userID); err != nil {
 				log.Error("failed to create new cart", sl.Err(err))
 				w.WriteHeader(422)
 				render.JSON(w, r, resp.Error("failed to create cart: "+err.Error()))
 				return
 			}
 			// Получаем номер созданной корзины
-			if err := c.GetActiveCartID(storage, userID); err != nil {
+			if err := c.GetActiveCartID(r.Context(), storage.DB, userID); err != nil {
 				log.Error("failed to get active cart id", sl.Err(err))
 				w.WriteHeader(422)
 				render.JSON(w, r, resp.Error("failed to get active cart id: "+err.Error()))
@@ -70,7 +70,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Заполняем слайс товарами для нужной корзины из БД
 		var ici *shop.InCartItem
-		icis, err := ici.GetInCartItems(storage, c.CartID)
+		icis, err := ici.GetInCartItems(r.Context(), storage.DB, c.CartID)
 		if err != nil {
 			log.Error("failed to get in cart items", sl.Err(err))
 			w.WriteHeader(422)
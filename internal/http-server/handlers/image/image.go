@@ -1,12 +1,17 @@
 package image
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	resp "portal/internal/lib/api/response"
-	minioServer "portal/internal/storage/minio"
+	"portal/internal/lib/imaging"
+	"portal/internal/lib/logger/sl"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
+	"time"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
@@ -14,13 +19,15 @@ import (
 
 type Request struct {
 	Name string
+	Size string // thumbnail, preview или full. Пусто - оригинал
 }
 
-type Response struct {
-	resp.Response
-}
+// Имена объектов уникальны для каждой загрузки, поэтому изображения можно долго хранить в кэше
+const cacheControl = "public, max-age=86400"
 
-func New(log *slog.Logger, miniosrv *minioServer.MinioProvider) http.HandlerFunc {
+// New serves image from the storage. If presign is set and the storage supports it, client is redirected
+// to short-lived presigned link and image bytes don't go through the API
+func New(log *slog.Logger, blobStore blob.BlobStore, presign bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.image.New"
 
@@ -41,33 +48,94 @@ func New(log *slog.Logger, miniosrv *minioServer.MinioProvider) http.HandlerFunc
 			return
 		}
 		req.Name = name[0]
+		req.Size = r.Form.Get("size")
+
+		objectName := req.Name
+		if req.Size != "" {
+			variant, ok := imaging.FindVariant(req.Size)
+			if !ok {
+				log.Error("unknown image size", slog.String("size", req.Size))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("unknown image size"))
+				return
+			}
+
+			// У изображений, загруженных до появления вариантов, отдается оригинал
+			variantName := imaging.VariantName(req.Name, variant)
+			if _, err := blobStore.Stat(r.Context(), variantName); err == nil {
+				objectName = variantName
+			} else if !errors.Is(err, storageHandler.ErrImageDoesNotExist) {
+				log.Error("failed to get image info from storage", sl.Err(err))
+				w.WriteHeader(422)
+				render.JSON(w, r, resp.Error("failed to get image from storage"))
+				return
+			}
+		}
 
-		file, err := miniosrv.DownloadImage(r.Context(), req.Name)
-		if err != nil && err.Error() == "Cant send photo: The specified key does not exist." {
+		info, err := blobStore.Stat(r.Context(), objectName)
+		if errors.Is(err, storageHandler.ErrImageDoesNotExist) {
 			log.Error("image with this name doesn't exist")
 			w.WriteHeader(406)
 			render.JSON(w, r, resp.Error("image with this name doesn't exist"))
 			return
 		}
 		if err != nil {
-			log.Error("failed to get image from minio")
+			log.Error("failed to get image info from storage", sl.Err(err))
 			w.WriteHeader(422)
-			render.JSON(w, r, resp.Error("failed to get image from minio"))
+			render.JSON(w, r, resp.Error("failed to get image from storage"))
 			return
 		}
-		defer file.Close()
 
-		log.Info("image successfully gotten")
+		if presign {
+			u, expiresAt, err := blobStore.PresignGet(r.Context(), objectName)
+			if err == nil {
+				// Перенаправление кэшируется меньше времени жизни ссылки, чтобы браузер не перешел по истекшей ссылке
+				w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(time.Until(expiresAt).Seconds())/2))
+				http.Redirect(w, r, u.String(), http.StatusFound)
+
+				log.Info("image redirected to presigned url", slog.String("name", objectName))
+				return
+			}
+			// Хранилище без подписанных ссылок отдает файл через API
+			if !errors.Is(err, blob.ErrPresignNotSupported) {
+				log.Error("failed to presign image url", sl.Err(err))
+				w.WriteHeader(422)
+				render.JSON(w, r, resp.Error("failed to get image from storage"))
+				return
+			}
+		}
+
+		file, err := blobStore.Get(r.Context(), objectName)
+		if err != nil {
+			log.Error("failed to get image from storage", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get image from storage"))
+			return
+		}
+		defer file.Close()
 
-		w.Header().Set("Content-Type", "image/png")
-		if _, err := io.Copy(w, file); err != nil {
-			fmt.Printf("Cant send photo: %v\n", err)
-			http.Error(w, "Can`t download photo!", http.StatusInternalServerError)
+		// Тип содержимого определяется по файлу, т.к. у старых объектов в хранилище он записан неверно
+		contentType := info.ContentType
+		header := make([]byte, 12)
+		if n, _ := io.ReadFull(file, header); n > 0 {
+			if format, err := imaging.Sniff(header[:n]); err == nil {
+				contentType = imaging.ContentType(format)
+			}
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			log.Error("failed to read image from storage", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get image from storage"))
 			return
 		}
 
-		log.Info("image successfully set to response")
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"`+info.ETag+`"`)
+		w.Header().Set("Cache-Control", cacheControl)
+
+		// ServeContent обрабатывает Range, If-None-Match и If-Modified-Since
+		http.ServeContent(w, r, "", info.LastModified, file)
 
-		render.JSON(w, r, resp.OK())
+		log.Info("image successfully set to response", slog.String("name", objectName))
 	}
 }
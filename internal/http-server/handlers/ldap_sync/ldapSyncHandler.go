@@ -0,0 +1,48 @@
+package ldapSyncHandler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	ldapSync "portal/internal/lib/ldap_sync"
+	"portal/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// New starts manual sync with syncCtx, the context of background jobs: the sync outlives the request
+// but is stopped together with the server
+func New(syncCtx context.Context, log *slog.Logger, syncer *ldapSync.Syncer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.ldapSync.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Ручной запуск синхронизации пользователей с AD. Синхронизация выполняется в фоне
+		// и не прерывается при завершении запроса, результат доступен в /api/ldap_sync_report
+		err := syncer.Start(syncCtx)
+		if err != nil {
+			if errors.Is(err, ldapSync.ErrSyncInProgress) {
+				log.Error("ldap sync is already in progress")
+				w.WriteHeader(409)
+				render.JSON(w, r, resp.Error("ldap sync is already in progress"))
+				return
+			}
+			log.Error("failed to start ldap sync", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to start ldap sync"))
+			return
+		}
+
+		log.Info("ldap sync started")
+
+		w.WriteHeader(202)
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,56 @@
+package ldapSyncReport
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	ldapSync "portal/internal/lib/ldap_sync"
+	"portal/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Report ldapSync.Report `json:"report"`
+}
+
+func New(log *slog.Logger, syncer *ldapSync.Syncer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.ldapSyncReport.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		report, ok := syncer.LastReport()
+		if !ok {
+			log.Error("no ldap sync report yet")
+			w.WriteHeader(404)
+			render.JSON(w, r, resp.Error("no ldap sync report yet"))
+			return
+		}
+
+		log.Info("ldap sync report gotten")
+
+		responseOK(w, r, log, report)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, report ldapSync.Report) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Report:   report,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
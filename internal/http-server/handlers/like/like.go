@@ -76,7 +76,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Делаем запрос в БД на добавление лайка к посту
 		var l news.Like
-		if err := l.NewLike(storage, userID, req.PostID); err != nil {
+		if err := l.NewLike(r.Context(), storage.DB, userID, req.PostID); err != nil {
 			log.Error("failed to add new like", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to add new like"))
@@ -87,7 +87,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Проверка наличия брони у пользователя в эту дату
 		var locker_reservation *reservation.LockerReservation
-		hasUserLockerReservation, err := locker_reservation.HasUserLockerReservationInDateRange(storage, userID, start, finish)
+		hasUserLockerReservation, err := locker_reservation.HasUserLockerReservationInDateRange(r.Context(), storage.DB, userID, start, finish)
 		if err != nil {
 			log.Error("failed to check has user locker reservation if date range", sl.Err(err))
 			w.WriteHeader(422)
@@ -102,7 +102,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		}
 
 		// Добавление записи бронирования в БД
-		err = locker_reservation.InsertLockerReservation(storage, req.LockerID, userID, start, finish)
+		err = locker_reservation.InsertLockerReservation(r.Context(), storage.DB, req.LockerID, userID, start, finish)
 		if err != nil {
 			log.Error("failed to reserve locker", sl.Err(err))
 			w.WriteHeader(422)
@@ -65,7 +65,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		// TO DO: нужна ли проверка на удаление собственное продирование удаляется
 		// Удаление записи брониварония из БД
 		var lockerReservation *reservation.LockerReservation
-		err = lockerReservation.DeleteLockerReservation(storage, req.LockerReservationID)
+		err = lockerReservation.DeleteLockerReservation(r.Context(), storage.DB, req.LockerReservationID)
 		if err != nil {
 			log.Error("failed to drop locker reservation", sl.Err(err))
 			w.WriteHeader(422)
@@ -76,7 +76,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Запрашиваем свободные места согласно заданным параметарм бронирования
 		var al reservation.ActualLocker
-		als, err := al.GetActualLockers(storage, req.Start, req.Finish)
+		als, err := al.GetActualLockers(r.Context(), storage.DB, req.Start, req.Finish)
 		if err != nil {
 			log.Error("failed to get locker reservation list", sl.Err(err))
 			w.WriteHeader(422)
@@ -91,14 +91,14 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			// Если место занято кем-то, то запрашиваем в 1С его ФИО и добавляем к инфо о брони
 			if ali.UserID != 0 {
 				var u user.User
-				err := u.GetUsername(storage, ali.UserID)
+				err := u.GetUsername(r.Context(), storage.DB, ali.UserID)
 				if err != nil {
 					log.Error("failed to get username", sl.Err(err))
 					w.WriteHeader(422)
 					render.JSON(w, r, resp.Error("failed to get username"))
 					return
 				}
-				err = u.GetUserInfo(storage, u.Username)
+				err = u.GetUserInfo(r.Context(), storage.DB, u.Username)
 				if err != nil {
 					log.Error("failed to get user info", sl.Err(err))
 					w.WriteHeader(422)
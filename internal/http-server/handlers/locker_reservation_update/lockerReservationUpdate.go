@@ -69,7 +69,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		// TO DO: нужна ли проверка, что обновляется свое бронирование
 		// Обновление записи бронирования в БД
 		var lockerReservation *reservation.LockerReservation
-		err = lockerReservation.UpdateLockerReservation(storage, req.LockerReservationID, req.LockerID, req.Start, req.Finish)
+		err = lockerReservation.UpdateLockerReservation(r.Context(), storage.DB, req.LockerReservationID, req.LockerID, req.Start, req.Finish)
 		if err != nil {
 			log.Error("failed to update locker reservation", sl.Err(err))
 			w.WriteHeader(422)
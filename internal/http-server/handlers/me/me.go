@@ -8,6 +8,7 @@ import (
 	"portal/internal/lib/logger/sl"
 	"portal/internal/lib/oauth"
 	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/role"
 	"portal/internal/storage/postgres/entities/user"
 
 	"github.com/go-chi/chi/middleware"
@@ -15,10 +16,12 @@ import (
 )
 
 type User struct {
-	UserID    int    `json:"user_id"`
-	Username  string `json:"username"`
-	Role      int    `json:"role"`
-	ImagePath string `json:"image_path"`
+	UserID      int      `json:"user_id"`
+	Username    string   `json:"username"`
+	Role        int      `json:"role"`
+	Roles       []int    `json:"roles"`
+	Permissions []string `json:"permissions"`
+	ImagePath   string   `json:"image_path"`
 }
 
 type Response struct {
@@ -65,7 +68,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Получаем путь фото пользователя из БД
 		var u user.User
-		err := u.GetImagePath(storage, userID)
+		err := u.GetImagePath(r.Context(), storage.DB, userID)
 		if err != nil {
 			log.Error("failed to get image path", sl.Err(err))
 			w.WriteHeader(422)
@@ -73,7 +76,24 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			return
 		}
 
-		user := User{UserID: userID, Username: username, Role: userRole, ImagePath: u.ImagePath}
+		// Получаем роли и права пользователя для отображения доступных разделов
+		var ro role.Role
+		roleIDs, err := ro.GetUserRoles(r.Context(), storage.DB, userID)
+		if err != nil {
+			log.Error("failed to get user roles", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get user roles"))
+			return
+		}
+		ps, err := ro.GetPermissionsByUsername(r.Context(), storage.DB, username)
+		if err != nil {
+			log.Error("failed to get user permissions", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get user permissions"))
+			return
+		}
+
+		user := User{UserID: userID, Username: username, Role: userRole, Roles: roleIDs, Permissions: ps, ImagePath: u.ImagePath}
 
 		responseOK(w, r, log, user)
 	}
@@ -39,38 +39,45 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			return
 		}
 
-		// Запрос cart_id для вызывающего user_id
-		var c shop.Cart
-		err := c.GetActiveCartID(storage, userID)
-		if err != nil {
-			// Если ошибка не об отсутствии корзины, то выход по стнадартной ошибке БД
-			if !errors.As(err, &storageHandler.ErrCartDoesNotExist) {
-				log.Error("failed to get active cart id", sl.Err(err))
-				w.WriteHeader(422)
-				render.JSON(w, r, resp.Error("failed to get active cart id"))
-				return
-			}
-			// Если ошибка выше была об отсутствии корзины, то создаем корзину
-			if err := c.NewCart(storage, userID); err != nil {
-				log.Error("failed to create new cart", sl.Err(err))
-				w.WriteHeader(422)
-				render.JSON(w, r, resp.Error("failed to create cart"))
-				return
+		// Поиск или создание корзины и оформление заказа выполняются в одной транзакции
+		var errMsg string
+		err := storage.WithTx(r.Context(), func(tx postgres.Querier) error {
+			// Запрос cart_id для вызывающего user_id
+			var c shop.Cart
+			err := c.GetActiveCartID(r.Context(), tx, userID)
+			if err != nil {
+				// Если ошибка не об отсутствии корзины, то выход по стнадартной ошибке БД
+				if !errors.Is(err, storageHandler.ErrCartDoesNotExist) {
+					errMsg = "failed to get active cart id"
+					return err
+				}
+				// Если ошибка выше была об отсутствии корзины, то создаем корзину
+				if err := c.NewCart(r.Context(), tx, userID); err != nil {
+					errMsg = "failed to create cart"
+					return err
+				}
+				// Получаем номер созданной корзины
+				if err := c.GetActiveCartID(r.Context(), tx, userID); err != nil {
+					errMsg = "failed to get active cart id"
+					return err
+				}
 			}
-			// Получаем номер созданной корзины
-			if err := c.GetActiveCartID(storage, userID); err != nil {
-				log.Error("failed to get active cart id", sl.Err(err))
-				w.WriteHeader(422)
-				render.JSON(w, r, resp.Error("failed to get active cart id"))
-				return
+
+			// Переводим корзину с cartID в неактивное состояние
+			if err := c.UpdateCartToInactive(r.Context(), tx, c.CartID); err != nil {
+				errMsg = "failed to make order"
+				return err
 			}
-		}
 
-		// Переводим корзину с cartID в неактивное состояние
-		if err := c.UpdateCartToInactive(storage, c.CartID); err != nil {
-			log.Error("failed to make order", sl.Err(err))
+			return nil
+		})
+		if err != nil {
+			if errMsg == "" {
+				errMsg = "failed to make order"
+			}
+			log.Error(errMsg, sl.Err(err))
 			w.WriteHeader(422)
-			render.JSON(w, r, resp.Error("failed to make order"))
+			render.JSON(w, r, resp.Error(errMsg))
 			return
 		}
 
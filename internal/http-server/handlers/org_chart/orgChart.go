@@ -0,0 +1,62 @@
+package orgChart
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	OrgChart []*user.OrgChartNode `json:"org_chart"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.orgChart.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Считываем параметры запроса из request. Без department возвращается вся организация
+		r.ParseForm()
+		department := r.Form.Get("department")
+
+		var n user.OrgChartNode
+		orgChart, err := n.GetOrgChart(r.Context(), storage.DB, department)
+		if err != nil {
+			log.Error("failed to get org chart", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get org chart"))
+			return
+		}
+
+		log.Info("org chart gotten", slog.String("department", department))
+
+		responseOK(w, r, log, orgChart)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, orgChart []*user.OrgChartNode) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		OrgChart: orgChart,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
@@ -0,0 +1,89 @@
+package orgChartChain
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Chain []user.OrgChartNode `json:"chain"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.orgChartChain.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var err error
+
+		var userID int
+
+		// Считываем параметры запроса из request
+		r.ParseForm()
+		rawUserID, ok := r.Form["user_id"]
+		if ok {
+			userID, err = strconv.Atoi(rawUserID[0])
+			if err != nil {
+				log.Error("failed to make int user id", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("failed to make int user id"))
+				return
+			}
+		} else {
+			// Получаем userID из токена авторизации, если не указан в параметре
+			tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+			userID, ok = tempUserID["user_id"]
+			if !ok {
+				// В токенах сервисных клиентов нет user_id, для них параметр обязателен
+				log.Error("no user id in token claims")
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("user_id is required"))
+				return
+			}
+		}
+
+		// Получаем цепочку руководителей пользователя от непосредственного до высшего
+		var n user.OrgChartNode
+		nodes, err := n.GetChainOfCommand(r.Context(), storage.DB, userID)
+		if err != nil {
+			log.Error("failed to get chain of command", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get chain of command"))
+			return
+		}
+
+		log.Info("chain of command gotten", slog.Int("user_id", userID))
+
+		responseOK(w, r, log, nodes)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, nodes []user.OrgChartNode) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Chain:    nodes,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
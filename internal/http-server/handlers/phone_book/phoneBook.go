@@ -2,28 +2,34 @@ package phoneBook
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	resp "portal/internal/lib/api/response"
 	"portal/internal/lib/logger/sl"
+	storageHandler "portal/internal/storage"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/user"
+	"strconv"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 )
 
 type EmployeeInfo struct {
+	UserID     int    `json:"user_id"`
 	FullName   string `json:"full_name"`
 	Position   string `json:"position"`
 	Department string `json:"department"`
 	Mail       string `json:"mail"`
 	Mobile     string `json:"mobile"`
+	ImagePath  string `json:"image_path"`
 }
 
 type Response struct {
 	resp.Response
 	EmployeesInfo []EmployeeInfo `json:"employees"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
 }
 
 func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
@@ -35,30 +41,57 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
+		// Считываем параметры запроса из request
+		r.ParseForm()
+		q := user.PhoneBookQuery{
+			Search:     r.Form.Get("search"),
+			Department: r.Form.Get("department"),
+			SortBy:     r.Form.Get("sort"),
+			Desc:       r.Form.Get("order") == "desc",
+			Cursor:     r.Form.Get("cursor"),
+		}
+		if rawLimit := r.Form.Get("limit"); rawLimit != "" {
+			limit, err := strconv.Atoi(rawLimit)
+			if err != nil {
+				log.Error("failed to make int limit", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("failed to make int limit"))
+				return
+			}
+			q.Limit = limit
+		}
+
 		var u user.User
-		var eis []EmployeeInfo
-		us, err := u.GetAllUsersInfo(storage)
+		us, nextCursor, err := u.GetPhoneBookPage(r.Context(), storage.DB, q)
 		if err != nil {
+			if errors.Is(err, storageHandler.ErrInvalidCursor) || errors.Is(err, storageHandler.ErrInvalidSortField) {
+				log.Error("invalid request", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error(errors.Unwrap(err).Error()))
+				return
+			}
 			log.Error("failed to get users info", sl.Err(err))
 			w.WriteHeader(422)
 			render.JSON(w, r, resp.Error("failed to get users info"))
 			return
 		}
 
+		eis := make([]EmployeeInfo, 0, len(us))
 		for _, u := range us {
-			eis = append(eis, EmployeeInfo{FullName: u.FullName, Position: u.Position, Department: u.Department, Mail: u.Mail, Mobile: u.Mobile})
+			eis = append(eis, EmployeeInfo{UserID: u.UserID, FullName: u.FullName, Position: u.Position, Department: u.Department, Mail: u.Mail, Mobile: u.Mobile, ImagePath: u.ImagePath})
 		}
 
 		log.Info("users gotten")
 
-		responseOK(w, r, log, eis)
+		responseOK(w, r, log, eis, nextCursor)
 	}
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, eis []EmployeeInfo) {
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, eis []EmployeeInfo, nextCursor string) {
 	response, err := json.Marshal(Response{
 		Response:      resp.OK(),
 		EmployeesInfo: eis,
+		NextCursor:    nextCursor,
 	})
 	if err != nil {
 		log.Error("failed to process response", sl.Err(err))
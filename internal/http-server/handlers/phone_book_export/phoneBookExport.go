@@ -0,0 +1,245 @@
+package phoneBookExport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	formatVCard = "vcf"
+	formatCSV   = "csv"
+)
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.phoneBookExport.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Считываем параметры запроса из request
+		r.ParseForm()
+		format := r.Form.Get("format")
+		if format != formatVCard && format != formatCSV {
+			log.Error("invalid format", slog.String("format", format))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("format must be vcf or csv"))
+			return
+		}
+
+		// Выгружаем справочник постранично теми же запросами, что и phoneBook.New.
+		// Первая страница запрашивается до отправки заголовков, чтобы ошибку можно было вернуть клиенту
+		q := user.PhoneBookQuery{
+			Search:     r.Form.Get("search"),
+			Department: r.Form.Get("department"),
+			Limit:      user.PhoneBookMaxLimit,
+		}
+		var u user.User
+		page, nextCursor, err := u.GetPhoneBookPage(r.Context(), storage.DB, q)
+		if err != nil {
+			log.Error("failed to get users info", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get users info"))
+			return
+		}
+
+		var pw pageWriter
+		var contentType, filename string
+		switch format {
+		case formatVCard:
+			pw = newVCardWriter(w)
+			contentType, filename = "text/vcard; charset=utf-8", "phone_book.vcf"
+		case formatCSV:
+			pw = newCSVWriter(w)
+			contentType, filename = "text/csv; charset=utf-8", "phone_book.csv"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+		// Каждая страница отправляется клиенту сразу, весь справочник в памяти не хранится.
+		// После отправки первой страницы об ошибке можно только записать в лог: ответ будет неполным
+		total := 0
+		for {
+			if err := pw.writePage(page); err != nil {
+				log.Error("failed to write phone book", sl.Err(err))
+				return
+			}
+			total += len(page)
+			if nextCursor == "" {
+				break
+			}
+
+			q.Cursor = nextCursor
+			page, nextCursor, err = u.GetPhoneBookPage(r.Context(), storage.DB, q)
+			if err != nil {
+				log.Error("failed to get users info", sl.Err(err))
+				return
+			}
+		}
+
+		log.Info("phone book exported", slog.String("format", format), slog.Int("users", total))
+	}
+}
+
+// pageWriter writes page of users to the response and flushes it
+type pageWriter interface {
+	writePage(us []user.User) error
+}
+
+type vCardWriter struct {
+	bw *bufio.Writer
+}
+
+func newVCardWriter(w io.Writer) *vCardWriter {
+	return &vCardWriter{bw: bufio.NewWriter(w)}
+}
+
+func (vw *vCardWriter) writePage(us []user.User) error {
+	writeVCards(vw.bw, us)
+	return vw.bw.Flush()
+}
+
+// csvWriter writes RFC 4180 CSV. BOM and header are written before the first page
+type csvWriter struct {
+	w      io.Writer
+	cw     *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	return &csvWriter{w: w, cw: cw}
+}
+
+func (cw *csvWriter) writePage(us []user.User) error {
+	if !cw.header {
+		// BOM нужен Excel для определения UTF-8
+		if _, err := io.WriteString(cw.w, "\ufeff"); err != nil {
+			return err
+		}
+		if err := cw.cw.Write([]string{"full_name", "position", "department", "mobile", "mail"}); err != nil {
+			return err
+		}
+		cw.header = true
+	}
+
+	if err := writeCSV(cw.cw, us); err != nil {
+		return err
+	}
+	cw.cw.Flush()
+
+	return cw.cw.Error()
+}
+
+// writeVCards writes one vCard 3.0 entry per user
+func writeVCards(buf *bufio.Writer, us []user.User) {
+	for _, u := range us {
+		writeVCardLine(buf, "BEGIN:VCARD")
+		writeVCardLine(buf, "VERSION:3.0")
+		// ФИО хранится как "Фамилия Имя Отчество", N: Фамилия;Имя;Отчество;;
+		names := strings.Fields(u.FullName)
+		n := make([]string, 3)
+		for i := 0; i < len(names) && i < 3; i++ {
+			n[i] = escapeVCard(names[i])
+		}
+		writeVCardLine(buf, "N:"+strings.Join(n, ";")+";;")
+		writeVCardLine(buf, "FN:"+escapeVCard(u.FullName))
+		if u.Position != "" {
+			writeVCardLine(buf, "TITLE:"+escapeVCard(u.Position))
+		}
+		if u.Department != "" {
+			writeVCardLine(buf, "ORG:"+escapeVCard(u.Department))
+		}
+		if u.Mobile != "" && u.Mobile != "-" {
+			writeVCardLine(buf, "TEL;TYPE=CELL:"+escapeVCard(u.Mobile))
+		}
+		if u.Mail != "" {
+			writeVCardLine(buf, "EMAIL;TYPE=INTERNET:"+escapeVCard(u.Mail))
+		}
+		writeVCardLine(buf, "END:VCARD")
+	}
+}
+
+// escapeVCard escapes text value according to RFC 2426
+func escapeVCard(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `,`, `\,`, `;`, `\;`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// writeVCardLine writes content line folded to 75 octets without splitting UTF-8 characters
+func writeVCardLine(buf *bufio.Writer, line string) {
+	const maxOctets = 75
+	for width := maxOctets; len(line) > width; width = maxOctets - 1 {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+// writeCSV writes users as CSV rows
+func writeCSV(cw *csv.Writer, us []user.User) error {
+	for _, u := range us {
+		mobile := u.Mobile
+		if mobile == "-" {
+			mobile = ""
+		}
+		row := []string{u.FullName, u.Position, u.Department, mobile, u.Mail}
+		for i := range row {
+			row[i] = escapeCSVCell(row[i])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// escapeCSVCell prevents CSV formula injection: spreadsheet treats cells starting with these characters
+// as formulas, the leading quote makes them text. Phone numbers like "+7 999 000-00-00" are kept as is,
+// the export is imported into desk phones and address books
+func escapeCSVCell(value string) string {
+	if value == "" || !strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return value
+	}
+	if (value[0] == '+' || value[0] == '-') && isPhoneNumber(value[1:]) {
+		return value
+	}
+	return "'" + value
+}
+
+// isPhoneNumber reports whether value contains only digits, spaces, parentheses and dashes, at least one digit
+func isPhoneNumber(value string) bool {
+	hasDigit := false
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r == ' ' || r == '(' || r == ')' || r == '-':
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
@@ -0,0 +1,135 @@
+package phoneBookExport
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEscapeVCard(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain", value: "Иванов Иван", want: "Иванов Иван"},
+		{name: "comma", value: "Отдел продаж, филиал", want: `Отдел продаж\, филиал`},
+		{name: "semicolon", value: "a;b", want: `a\;b`},
+		{name: "backslash", value: `a\b`, want: `a\\b`},
+		{name: "newline", value: "a\nb", want: `a\nb`},
+		{name: "crlf", value: "a\r\nb", want: `a\nb`},
+		{name: "escaped before", value: `\,`, want: `\\\,`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeVCard(tt.value); got != tt.want {
+				t.Errorf("escapeVCard(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteVCardLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "short", line: "FN:Иванов", want: "FN:Иванов\r\n"},
+		{name: "exactly 75 octets", line: strings.Repeat("a", 75), want: strings.Repeat("a", 75) + "\r\n"},
+		{
+			name: "76 octets",
+			line: strings.Repeat("a", 76),
+			want: strings.Repeat("a", 75) + "\r\n a\r\n",
+		},
+		{
+			name: "several folds",
+			line: strings.Repeat("a", 75+74+1),
+			want: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n a\r\n",
+		},
+		{
+			// Двухбайтовый символ на границе переносится целиком на следующую строку
+			name: "rune on boundary",
+			line: strings.Repeat("a", 74) + "Я",
+			want: strings.Repeat("a", 74) + "\r\n Я\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			buf := bufio.NewWriter(&sb)
+			writeVCardLine(buf, tt.line)
+			if err := buf.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("writeVCardLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteVCardLineUnfold(t *testing.T) {
+	lines := []string{
+		"FN:" + strings.Repeat("Иванов ", 40),
+		"ORG:" + strings.Repeat("x", 10) + strings.Repeat("Ж", 100),
+		"TITLE:" + strings.Repeat("中", 60),
+	}
+
+	for _, line := range lines {
+		var sb strings.Builder
+		buf := bufio.NewWriter(&sb)
+		writeVCardLine(buf, line)
+		if err := buf.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		out := strings.TrimSuffix(sb.String(), "\r\n")
+		for _, physical := range strings.Split(out, "\r\n") {
+			if len(physical) > 75 {
+				t.Errorf("line %q is %d octets, want at most 75", physical, len(physical))
+			}
+			if !utf8.ValidString(physical) {
+				t.Errorf("line %q splits UTF-8 character", physical)
+			}
+		}
+
+		// Разворачивание по RFC 2425 должно вернуть исходную строку
+		if unfolded := strings.ReplaceAll(out, "\r\n ", ""); unfolded != line {
+			t.Errorf("unfolded = %q, want %q", unfolded, line)
+		}
+	}
+}
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "empty", value: "", want: ""},
+		{name: "plain", value: "Иванов", want: "Иванов"},
+		{name: "formula", value: "=HYPERLINK(\"x\")", want: "'=HYPERLINK(\"x\")"},
+		{name: "phone", value: "+7 999 000-00-00", want: "+7 999 000-00-00"},
+		{name: "phone with parentheses", value: "+7 (999) 000-00-00", want: "+7 (999) 000-00-00"},
+		{name: "negative number", value: "-1", want: "-1"},
+		{name: "plus formula", value: "+1+cmd|' /C calc'!A0", want: "'+1+cmd|' /C calc'!A0"},
+		{name: "minus formula", value: "-2+3", want: "'-2+3"},
+		{name: "plus without digits", value: "+ -", want: "'+ -"},
+		{name: "at", value: "@SUM(A1)", want: "'@SUM(A1)"},
+		{name: "tab", value: "\t=1", want: "'\t=1"},
+		{name: "carriage return", value: "\r=1", want: "'\r=1"},
+		{name: "formula char inside", value: "a=b", want: "a=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeCSVCell(tt.value); got != tt.want {
+				t.Errorf("escapeCSVCell(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package postImage
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"portal/internal/lib/imaging"
+	"portal/internal/lib/logger/sl"
+	postImageLib "portal/internal/lib/post_image"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/news"
+	"strconv"
+
+	resp "portal/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Image news.PostImage `json:"image"`
+}
+
+// New adds single image to the post. Form fields: post_id, image, alt_text and optional position, by default image is added to the end
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.postImage.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		postID, err := strconv.Atoi(r.FormValue("post_id"))
+		if err != nil {
+			log.Error("failed to make int post id", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to make int post id"))
+			return
+		}
+
+		src, hdr, err := r.FormFile("image")
+		if err != nil {
+			log.Error("failed to get image from request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to get image from request body"))
+			return
+		}
+		src.Close()
+
+		if _, err := imaging.CheckUpload(hdr); err != nil {
+			log.Error("invalid image", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		altText := r.FormValue("alt_text")
+		var position *int
+		if rawPosition := r.FormValue("position"); rawPosition != "" {
+			p, err := strconv.Atoi(rawPosition)
+			if err != nil {
+				log.Error("failed to make int position", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("failed to make int position"))
+				return
+			}
+			position = &p
+		}
+
+		// Добавление и перемещение фото выполняются в одной транзакции под блокировкой поста
+		var pi news.PostImage
+		errMsg := "failed to add image to post"
+		err = storage.WithTx(r.Context(), func(tx postgres.Querier) error {
+			var err error
+			if pi, err = postImageLib.Upload(r.Context(), tx, blobStore, postID, hdr, altText); err != nil {
+				return err
+			}
+
+			// Перемещаем фото на указанную позицию
+			if position != nil {
+				errMsg = "failed to move post image"
+				return pi.MovePostImage(r.Context(), tx, pi.PostImageID, *position, altText)
+			}
+			return nil
+		})
+		if err != nil {
+			// Транзакция откатилась, загруженное фото больше не нужно
+			if pi.ObjectName != "" {
+				postImageLib.RemoveObjects(r.Context(), blobStore, []string{pi.ObjectName})
+			}
+
+			log.Error(errMsg, sl.Err(err))
+			if errors.Is(err, storageHandler.ErrPostDoesNotExist) {
+				w.WriteHeader(404)
+				render.JSON(w, r, resp.Error("post does not exist"))
+				return
+			}
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error(errMsg))
+			return
+		}
+
+		log.Info("post image successfully added", slog.Int("post_id", postID), slog.Int("post_image_id", pi.PostImageID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Image:    pi,
+		})
+	}
+}
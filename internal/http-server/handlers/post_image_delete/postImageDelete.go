@@ -0,0 +1,93 @@
+package postImageDelete
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	postImage "portal/internal/lib/post_image"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/news"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	PostImageID int `json:"post_image_id" validate:"required"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.postImageDelete.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		var pi news.PostImage
+		if err := pi.DeletePostImage(r.Context(), storage, req.PostImageID); err != nil {
+			log.Error("failed to delete post image", sl.Err(err))
+			if errors.Is(err, storageHandler.ErrPostImageDoesNotExist) {
+				w.WriteHeader(404)
+				render.JSON(w, r, resp.Error("post image does not exist"))
+				return
+			}
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to delete post image"))
+			return
+		}
+
+		// Удаляем фото и его варианты из хранилища. У старых записей имени объекта может не быть
+		if pi.ObjectName != "" {
+			for _, name := range postImage.ObjectNames(pi.ObjectName) {
+				if err := blobStore.Delete(r.Context(), name); err != nil {
+					log.Error("failed to remove image from minio", sl.Err(err))
+					w.WriteHeader(422)
+					render.JSON(w, r, resp.Error("failed to remove image from minio"))
+					return
+				}
+			}
+		}
+
+		log.Info("post image successfully deleted", slog.Int("post_image_id", req.PostImageID))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
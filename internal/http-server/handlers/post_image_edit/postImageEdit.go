@@ -0,0 +1,90 @@
+package postImageEdit
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/news"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Position - новая позиция фото среди фото поста, начиная с 0. Остальные фото сдвигаются
+type Request struct {
+	PostImageID int    `json:"post_image_id" validate:"required"`
+	Position    int    `json:"position" validate:"min=0"`
+	AltText     string `json:"alt_text" validate:"max=256"`
+}
+
+type Response struct {
+	resp.Response
+	Image news.PostImage `json:"image"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.postImageEdit.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		var pi news.PostImage
+		if err := pi.UpdatePostImage(r.Context(), storage, req.PostImageID, req.Position, req.AltText); err != nil {
+			log.Error("failed to update post image", sl.Err(err))
+			if errors.Is(err, storageHandler.ErrPostImageDoesNotExist) {
+				w.WriteHeader(404)
+				render.JSON(w, r, resp.Error("post image does not exist"))
+				return
+			}
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to update post image"))
+			return
+		}
+
+		log.Info("post image successfully updated", slog.Int("post_image_id", req.PostImageID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Image:    pi,
+		})
+	}
+}
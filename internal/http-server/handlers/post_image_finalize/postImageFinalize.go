@@ -0,0 +1,127 @@
+package postImageFinalize
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/imaging"
+	"portal/internal/lib/logger/sl"
+	postImage "portal/internal/lib/post_image"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/news"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Name - имя из /api/post_image_upload_url. Position - позиция фото, по умолчанию фото добавляется в конец
+type Request struct {
+	PostID   int    `json:"post_id" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	AltText  string `json:"alt_text" validate:"max=256"`
+	Position *int   `json:"position" validate:"omitempty,min=0"`
+}
+
+type Response struct {
+	resp.Response
+	Image news.PostImage `json:"image"`
+}
+
+// Ошибки проверки загруженного объекта, о которых сообщается клиенту
+var requestErrors = []error{
+	postImage.ErrInvalidUploadName,
+	postImage.ErrAlreadyFinalized,
+	imaging.ErrSizeOutOfLimit,
+	imaging.ErrFormatNotAllowed,
+	imaging.ErrImageTooLarge,
+}
+
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.postImageFinalize.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		// Добавление и перемещение фото выполняются в одной транзакции: при ошибке фото не остается
+		// привязанным к посту, и повторный запрос с тем же именем проходит заново.
+		// Перезаписанные объекты при откате не удаляются: их заменит повторный запрос или удалит blob gc
+		var pi news.PostImage
+		errMsg := "failed to finalize post image"
+		err = storage.WithTx(r.Context(), func(tx postgres.Querier) error {
+			var err error
+			if pi, err = postImage.Finalize(r.Context(), tx, blobStore, req.PostID, req.Name, req.AltText); err != nil {
+				return err
+			}
+
+			// Перемещаем фото на указанную позицию
+			if req.Position != nil {
+				errMsg = "failed to move post image"
+				return pi.MovePostImage(r.Context(), tx, pi.PostImageID, *req.Position, req.AltText)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error(errMsg, sl.Err(err))
+			if errors.Is(err, storageHandler.ErrImageDoesNotExist) {
+				w.WriteHeader(404)
+				render.JSON(w, r, resp.Error("image is not uploaded"))
+				return
+			}
+			for _, reqErr := range requestErrors {
+				if errors.Is(err, reqErr) {
+					w.WriteHeader(400)
+					render.JSON(w, r, resp.Error(reqErr.Error()))
+					return
+				}
+			}
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error(errMsg))
+			return
+		}
+
+		log.Info("post image successfully finalized", slog.Int("post_id", req.PostID), slog.Int("post_image_id", pi.PostImageID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Image:    pi,
+		})
+	}
+}
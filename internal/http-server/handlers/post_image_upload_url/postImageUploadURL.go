@@ -0,0 +1,99 @@
+package postImageUploadURL
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	postImage "portal/internal/lib/post_image"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/news"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	PostID int `json:"post_id" validate:"required"`
+}
+
+// Upload - ссылка для загрузки фото методом PUT напрямую в MinIO. После загрузки нужно вызвать /api/post_image_finalize с name
+type Response struct {
+	resp.Response
+	Upload postImage.PresignedUpload `json:"upload"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.postImageUploadURL.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		// Проверяем, что пост существует
+		var p news.Post
+		if err := p.GetText(r.Context(), storage.DB, req.PostID); err != nil {
+			log.Error("failed to get post", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get post"))
+			return
+		}
+
+		upload, err := postImage.NewPresignedUpload(r.Context(), blobStore, req.PostID)
+		if errors.Is(err, postImage.ErrUploadNotSupported) {
+			log.Error("direct uploads are not supported by the storage")
+			w.WriteHeader(501)
+			render.JSON(w, r, resp.Error("direct uploads are not supported, use /api/post_image"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to presign upload url", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to presign upload url"))
+			return
+		}
+
+		log.Info("upload url successfully issued", slog.Int("post_id", req.PostID), slog.String("name", upload.Name))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Upload:   upload,
+		})
+	}
+}
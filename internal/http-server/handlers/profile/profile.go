@@ -26,6 +26,15 @@ type Profile struct {
 	Mail       string `json:"mail"`
 	Mobile     string `json:"mobile"`
 	Chief      string `json:"chief"`
+	ChiefID    int    `json:"chief_id,omitempty"`
+
+	ExtraPhone       string   `json:"extra_phone"`
+	Office           string   `json:"office"`
+	Bio              string   `json:"bio"`
+	Birthday         string   `json:"birthday,omitempty"`
+	ShowBirthday     bool     `json:"show_birthday"`
+	Skills           []string `json:"skills"`
+	OverriddenFields []string `json:"overridden_fields"`
 }
 
 type Response struct {
@@ -46,7 +55,17 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		var userID int
 
-		// Считываем параметры запроса из request
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		tokenUserID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		// Считываем параметры запроса из request, если не указан user_id - возвращаем свой профиль
 		r.ParseForm()
 		rawUserID, ok := r.Form["user_id"]
 		if ok {
@@ -58,20 +77,12 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 				return
 			}
 		} else {
-			// Получаем userID из токена авторизации, если не указан в параметре
-			tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
-			userID, ok = tempUserID["user_id"]
-			if !ok {
-				log.Error("no user id in token claims")
-				w.WriteHeader(500)
-				render.JSON(w, r, resp.Error("no user id in token claims"))
-				return
-			}
+			userID = tokenUserID
 		}
 
 		// Получаем username из БД
 		var u user.User
-		err = u.GetUsername(storage, userID)
+		err = u.GetUsername(r.Context(), storage.DB, userID)
 		if err != nil {
 			log.Error("failed to get username", sl.Err(err))
 			w.WriteHeader(422)
@@ -80,7 +91,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		}
 
 		// Получаем user info из БД
-		err = u.GetUserInfo(storage, u.Username)
+		err = u.GetUserInfo(r.Context(), storage.DB, u.Username)
 		if err != nil {
 			log.Error("failed to get user info", sl.Err(err))
 			w.WriteHeader(422)
@@ -88,9 +99,39 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			return
 		}
 
+		// Получаем поля, заполненные пользователем на портале
+		err = u.GetPersonalInfo(r.Context(), storage.DB, userID)
+		if err != nil {
+			log.Error("failed to get personal info", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get personal info"))
+			return
+		}
+
+		// День рождения скрыт от других пользователей, если пользователь не разрешил его показывать
+		if !u.ShowBirthday && userID != tokenUserID {
+			u.Birthday = ""
+		}
+
 		log.Info("profile data successfully gotten")
 
-		responseOK(w, r, log, Profile{Username: u.Username, FullName: u.FullName, Position: u.Position, Department: u.Department, Mail: u.Mail, Mobile: u.Mobile, Chief: u.Chief})
+		responseOK(w, r, log, Profile{
+			Username:         u.Username,
+			FullName:         u.FullName,
+			Position:         u.Position,
+			Department:       u.Department,
+			Mail:             u.Mail,
+			Mobile:           u.Mobile,
+			Chief:            u.Chief,
+			ChiefID:          u.ChiefID,
+			ExtraPhone:       u.ExtraPhone,
+			Office:           u.Office,
+			Bio:              u.Bio,
+			Birthday:         u.Birthday,
+			ShowBirthday:     u.ShowBirthday,
+			Skills:           u.Skills,
+			OverriddenFields: u.OverriddenFields,
+		})
 	}
 }
 
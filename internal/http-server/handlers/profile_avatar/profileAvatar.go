@@ -0,0 +1,88 @@
+package profileAvatar
+
+import (
+	"log/slog"
+	"net/http"
+	"portal/internal/lib/avatar"
+	"portal/internal/lib/imaging"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+
+	resp "portal/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	ImagePath string         `json:"image_path"`
+	Images    map[int]string `json:"images"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.profileAvatar.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		// Забираем фото из тела запроса
+		src, hdr, err := r.FormFile("image")
+		if err != nil {
+			log.Error("failed to get image from request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to get image from request body"))
+			return
+		}
+		src.Close()
+
+		// Проверям соответсвие фото требованиям, формат определяется по содержимому файла
+		data, err := imaging.ReadUpload(hdr)
+		if err != nil {
+			log.Error("invalid image", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		img, _, err := imaging.Decode(data)
+		if err != nil {
+			log.Error("failed to decode image", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode image"))
+			return
+		}
+
+		// Загружаем миниатюры в MinIO и обновляем image_path
+		images, err := avatar.Save(r.Context(), log, storage, blobStore, userID, img)
+		if err != nil {
+			log.Error("failed to save avatar", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to save avatar"))
+			return
+		}
+
+		log.Info("avatar successfully uploaded")
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			ImagePath: images[avatar.Sizes[len(avatar.Sizes)-1]],
+			Images:    images,
+		})
+	}
+}
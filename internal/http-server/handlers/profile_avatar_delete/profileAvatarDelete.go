@@ -0,0 +1,48 @@
+package profileAvatarDelete
+
+import (
+	"log/slog"
+	"net/http"
+	"portal/internal/lib/avatar"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+
+	resp "portal/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.profileAvatarDelete.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		if err := avatar.Delete(r.Context(), log, storage, blobStore, userID); err != nil {
+			log.Error("failed to delete avatar", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to delete avatar"))
+			return
+		}
+
+		log.Info("avatar successfully deleted")
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,94 @@
+package profileAvatarImport
+
+import (
+	"log/slog"
+	"net/http"
+	"portal/internal/lib/avatar"
+	"portal/internal/lib/imaging"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/blob"
+	ldapServer "portal/internal/storage/ldap"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+
+	resp "portal/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	ImagePath string         `json:"image_path"`
+	Images    map[int]string `json:"images"`
+}
+
+// New imports avatar of the user from thumbnailPhoto attribute in AD
+func New(log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore, ldapsrv *ldapServer.LDAPServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.profileAvatarImport.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		var u user.User
+		if err := u.GetUsername(r.Context(), storage.DB, userID); err != nil {
+			log.Error("failed to get username", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get username"))
+			return
+		}
+
+		photo, err := ldapsrv.GetThumbnailPhoto(u.Username)
+		if err != nil {
+			log.Error("failed to get thumbnail photo from LDAP", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get thumbnail photo from LDAP"))
+			return
+		}
+
+		if len(photo) == 0 {
+			log.Error("thumbnail photo is not set in LDAP")
+			w.WriteHeader(404)
+			render.JSON(w, r, resp.Error("thumbnail photo is not set in LDAP"))
+			return
+		}
+
+		img, _, err := imaging.Decode(photo)
+		if err != nil {
+			log.Error("failed to decode thumbnail photo", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to decode thumbnail photo"))
+			return
+		}
+
+		images, err := avatar.Save(r.Context(), log, storage, blobStore, userID, img)
+		if err != nil {
+			log.Error("failed to save avatar", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to save avatar"))
+			return
+		}
+
+		log.Info("avatar successfully imported from LDAP")
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			ImagePath: images[avatar.Sizes[len(avatar.Sizes)-1]],
+			Images:    images,
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package profileOverride
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Исправление полей из каталога администратором. Не указанные поля не меняются,
+// поля из Reset снова будут заполнены из каталога при следующей синхронизации
+type Request struct {
+	UserID     int      `json:"user_id" validate:"required"`
+	FullName   *string  `json:"full_name" validate:"omitempty,max=150"`
+	Position   *string  `json:"position" validate:"omitempty,max=150"`
+	Department *string  `json:"department" validate:"omitempty,max=150"`
+	Mobile     *string  `json:"mobile" validate:"omitempty,max=25"`
+	Mail       *string  `json:"mail" validate:"omitempty,max=150"`
+	Reset      []string `json:"reset" validate:"dive,oneof=full_name position department mobile mail"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.profileOverride.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		// Такую ошибку встретим, если получили запрос с пустым телом.
+		// Обработаем её отдельно
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		values := map[string]string{}
+		for field, value := range map[string]*string{
+			user.FieldFullName:   req.FullName,
+			user.FieldPosition:   req.Position,
+			user.FieldDepartment: req.Department,
+			user.FieldMobile:     req.Mobile,
+			user.FieldMail:       req.Mail,
+		} {
+			if value != nil {
+				values[field] = *value
+			}
+		}
+
+		if len(values) == 0 && len(req.Reset) == 0 {
+			log.Error("no fields to override")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("no fields to override"))
+			return
+		}
+
+		var u user.User
+		if err := u.OverrideFields(r.Context(), storage.DB, req.UserID, values, req.Reset); err != nil {
+			log.Error("failed to override user fields", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to override user fields"))
+			return
+		}
+
+		log.Info("user fields successfully overridden", slog.Int("user_id", req.UserID), slog.Any("fields", values), slog.Any("reset", req.Reset))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -11,8 +11,6 @@ import (
 	"portal/internal/lib/oauth"
 	"portal/internal/storage/postgres"
 	reservation "portal/internal/storage/postgres/entities/reservation"
-	"portal/internal/structs/roles"
-	"slices"
 	"time"
 
 	"github.com/go-chi/chi/middleware"
@@ -39,26 +37,6 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем запрещенные роли
-		restrictedRoles := []int{roles.UserWithOutReservation}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if slices.Contains(restrictedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		var req Request
 
 		// Декодируем json запроса
@@ -109,7 +87,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Проверка наличия брони у пользователя в эту дату
 		var reservation *reservation.Reservation
-		hasUserReservation, err := reservation.HasUserReservationInDateRange(storage, userID, start, finish)
+		hasUserReservation, err := reservation.HasUserReservationInDateRange(r.Context(), storage.DB, userID, start, finish)
 		if err != nil {
 			log.Error("failed to check has user reservation if date range", sl.Err(err))
 			w.WriteHeader(422)
@@ -124,7 +102,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		}
 
 		// Добавление записи бронирования в БД
-		err = reservation.InsertReservation(storage, req.PlaceID, userID, start, finish)
+		err = reservation.InsertReservation(r.Context(), storage.DB, req.PlaceID, userID, start, finish)
 		if err != nil {
 			log.Error("failed to reserve place", sl.Err(err))
 			w.WriteHeader(422)
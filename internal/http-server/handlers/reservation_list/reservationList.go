@@ -86,7 +86,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		// Запрашиваем свободные места согласно заданным параметарм бронирования
 		var ap reservation.ActualPlace
 		// TO DO: проработать поиск по параметрам рабочего места
-		aps, err := ap.GetActualPlaces(storage, req.Properties, req.Start, req.Finish)
+		aps, err := ap.GetActualPlaces(r.Context(), storage.DB, req.Properties, req.Start, req.Finish)
 		if err != nil {
 			log.Error("failed to get reservation list", sl.Err(err))
 			w.WriteHeader(422)
@@ -101,14 +101,14 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			// Если место занято кем-то, то запрашиваем в 1С его ФИО и добавляем к инфо о брони
 			if api.UserID != 0 {
 				var u user.User
-				err := u.GetUsername(storage, api.UserID)
+				err := u.GetUsername(r.Context(), storage.DB, api.UserID)
 				if err != nil {
 					log.Error("failed to get username", sl.Err(err))
 					w.WriteHeader(422)
 					render.JSON(w, r, resp.Error("failed to get username"))
 					return
 				}
-				err = u.GetUserInfo(storage, u.Username)
+				err = u.GetUserInfo(r.Context(), storage.DB, u.Username)
 				if err != nil {
 					log.Error("failed to get user info", sl.Err(err))
 					w.WriteHeader(422)
@@ -7,11 +7,8 @@ import (
 	"net/http"
 	resp "portal/internal/lib/api/response"
 	"portal/internal/lib/logger/sl"
-	"portal/internal/lib/oauth"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/reservation"
-	"portal/internal/structs/roles"
-	"slices"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -39,26 +36,6 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем запрещенные роли
-		restrictedRoles := []int{roles.UserWithOutReservation}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if slices.Contains(restrictedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		var req Request
 
 		// Декодируем json запроса
@@ -92,7 +69,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 		// TO DO: нужна ли проверка, что обновляется свое бронирование
 		// Обновление записи бронирования в БД
 		var reservation *reservation.Reservation
-		err = reservation.UpdateReservation(storage, req.ReservationID, req.PlaceID, req.Start, req.Finish)
+		err = reservation.UpdateReservation(r.Context(), storage.DB, req.ReservationID, req.PlaceID, req.Start, req.Finish)
 		if err != nil {
 			log.Error("failed to update reservation", sl.Err(err))
 			w.WriteHeader(422)
@@ -0,0 +1,84 @@
+package roleAudit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/role"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	UserID int
+}
+
+type Response struct {
+	resp.Response
+	Audit []role.Audit `json:"audit"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.roleAudit.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		var err error
+
+		// Считываем параметры запроса из request
+		r.ParseForm()
+		rawUserID, ok := r.Form["user_id"]
+		if !ok {
+			log.Error("empty user id parameter")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty user id parameter"))
+			return
+		}
+		req.UserID, err = strconv.Atoi(rawUserID[0])
+		if err != nil {
+			log.Error("failed to make int user id", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to make int user id"))
+			return
+		}
+
+		// Получаем историю изменения ролей пользователя
+		var a role.Audit
+		as, err := a.GetAuditByUserID(r.Context(), storage.DB, req.UserID)
+		if err != nil {
+			log.Error("failed to get role audit", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get role audit"))
+			return
+		}
+
+		log.Info("role audit gotten", slog.Int("user_id", req.UserID))
+
+		responseOK(w, r, log, as)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, audit []role.Audit) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Audit:    audit,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
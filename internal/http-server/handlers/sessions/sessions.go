@@ -0,0 +1,82 @@
+package sessions
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type SessionInfo struct {
+	user.Session
+	IsCurrent bool `json:"is_current"`
+}
+
+type Response struct {
+	resp.Response
+	Sessions []SessionInfo `json:"sessions"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.sessions.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		// Получаем ID текущей сессии из токена авторизации
+		currentSessionID, _ := r.Context().Value(oauth.SessionContext).(string)
+
+		var s user.Session
+		ss, err := s.GetSessionsByUserID(r.Context(), storage.DB, userID)
+		if err != nil {
+			log.Error("failed to get sessions", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get sessions"))
+			return
+		}
+
+		sis := []SessionInfo{}
+		for _, s := range ss {
+			sis = append(sis, SessionInfo{Session: s, IsCurrent: s.SessionID == currentSessionID})
+		}
+
+		log.Info("sessions gotten")
+
+		responseOK(w, r, log, sis)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, sessions []SessionInfo) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Sessions: sessions,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
@@ -31,7 +31,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Получаем слайс товаров
 		var i shop.Item
-		is, err := i.GetItems(storage)
+		is, err := i.GetItems(r.Context(), storage.DB)
 		if err != nil {
 			log.Error("failed to get shop list", sl.Err(err))
 			w.WriteHeader(422)
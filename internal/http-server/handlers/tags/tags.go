@@ -29,7 +29,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		var tag news.Tag
 		var tags []news.Tag
-		tags, err := tag.GetTags(storage)
+		tags, err := tag.GetTags(r.Context(), storage.DB)
 		if err != nil {
 			log.Error("failed to get tags", sl.Err(err))
 			w.WriteHeader(422)
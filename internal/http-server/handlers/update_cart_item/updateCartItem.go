@@ -66,7 +66,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		// Обновление предмета в корзине
 		var ici *shop.InCartItem
-		err = ici.UpdateInCartItem(storage, req.InCartItemID, req.Quantity)
+		err = ici.UpdateInCartItem(r.Context(), storage.DB, req.InCartItemID, req.Quantity)
 		if err != nil {
 			log.Error("failed to update item", sl.Err(err))
 			w.WriteHeader(422)
@@ -46,7 +46,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		var lockerReserv reservation.LockerReservation
 		var lockerReservations []reservation.LockerReservation
-		lockerReservations, err := lockerReserv.GetLockerReservationsByUserID(storage, userID)
+		lockerReservations, err := lockerReserv.GetLockerReservationsByUserID(r.Context(), storage.DB, userID)
 		if err != nil {
 			log.Error("failed to get locker reservation list", sl.Err(err))
 			w.WriteHeader(422)
@@ -60,7 +60,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			lri := LockerReservationInfo{LockerReservation: lockerReserv}
 
 			var locker reservation.Locker
-			err = locker.GetLockerName(storage, lockerReserv.LockerID)
+			err = locker.GetLockerName(r.Context(), storage.DB, lockerReserv.LockerID)
 			if err != nil {
 				log.Error("failed to get locker name", sl.Err(err))
 				w.WriteHeader(422)
@@ -8,8 +8,6 @@ import (
 	"portal/internal/lib/logger/sl"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities/reservation"
-	"portal/internal/structs/roles"
-	"slices"
 
 	"portal/internal/lib/oauth"
 
@@ -36,26 +34,6 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// Определяем запрещенные роли
-		restrictedRoles := []int{roles.UserWithOutReservation}
-
-		// Получаем user role из токена авторизации
-		role := r.Context().Value(oauth.ScopeContext).(int)
-		if role == 0 {
-			log.Error("no user role in token")
-			w.WriteHeader(500)
-			render.JSON(w, r, resp.Error("no user role in token"))
-			return
-		}
-
-		//  Проверяем доступно ли действие для роли текущего пользователя
-		if slices.Contains(restrictedRoles, role) {
-			log.Error("access was denied")
-			w.WriteHeader(403)
-			render.JSON(w, r, resp.Error("access was denied"))
-			return
-		}
-
 		// Получаем userID из токена авторизации
 		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
 		userID, ok := tempUserID["user_id"]
@@ -68,7 +46,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 
 		var reserv reservation.Reservation
 		var reservations []reservation.Reservation
-		reservations, err := reserv.GetReservationsByUserID(storage, userID)
+		reservations, err := reserv.GetReservationsByUserID(r.Context(), storage.DB, userID)
 		if err != nil {
 			log.Error("failed to get reservation list", sl.Err(err))
 			w.WriteHeader(422)
@@ -82,7 +60,7 @@ func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
 			ri := ReservationInfo{Reservation: reserv}
 
 			var place reservation.Place
-			err = place.GetPlaceName(storage, reserv.PlaceID)
+			err = place.GetPlaceName(r.Context(), storage.DB, reserv.PlaceID)
 			if err != nil {
 				log.Error("failed to get place name", sl.Err(err))
 				w.WriteHeader(422)
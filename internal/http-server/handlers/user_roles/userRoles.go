@@ -0,0 +1,85 @@
+package userRoles
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/role"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	UserID int
+}
+
+type Response struct {
+	resp.Response
+	UserID int   `json:"user_id"`
+	Roles  []int `json:"roles"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.userRoles.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		var err error
+
+		// Считываем параметры запроса из request
+		r.ParseForm()
+		rawUserID, ok := r.Form["user_id"]
+		if !ok {
+			log.Error("empty user id parameter")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty user id parameter"))
+			return
+		}
+		req.UserID, err = strconv.Atoi(rawUserID[0])
+		if err != nil {
+			log.Error("failed to make int user id", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to make int user id"))
+			return
+		}
+
+		var ro role.Role
+		roleIDs, err := ro.GetUserRoles(r.Context(), storage.DB, req.UserID)
+		if err != nil {
+			log.Error("failed to get user roles", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get user roles"))
+			return
+		}
+
+		log.Info("user roles gotten", slog.Int("user_id", req.UserID))
+
+		responseOK(w, r, log, req.UserID, roleIDs)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, userID int, roles []int) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		UserID:   userID,
+		Roles:    roles,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
@@ -0,0 +1,100 @@
+package viewsHistory
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/counter"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Период по умолчанию, если from не передан
+const defaultDays = 30
+
+type Request struct {
+	From time.Time
+	To   time.Time
+}
+
+type Response struct {
+	resp.Response
+	Views []counter.DailyCounter `json:"views"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.viewsHistory.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		var err error
+
+		// Считываем параметры запроса из request, даты в формате 2006-01-02
+		r.ParseForm()
+		req.To = time.Now()
+		if rawTo := r.Form.Get("to"); rawTo != "" {
+			req.To, err = time.Parse(time.DateOnly, rawTo)
+			if err != nil {
+				log.Error("failed to parse to date", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("failed to parse to date"))
+				return
+			}
+		}
+		req.From = req.To.AddDate(0, 0, -defaultDays+1)
+		if rawFrom := r.Form.Get("from"); rawFrom != "" {
+			req.From, err = time.Parse(time.DateOnly, rawFrom)
+			if err != nil {
+				log.Error("failed to parse from date", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("failed to parse from date"))
+				return
+			}
+		}
+		if req.From.After(req.To) {
+			log.Error("from date is after to date")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("from date is after to date"))
+			return
+		}
+
+		// Получаем просмотры портала по дням
+		var dc counter.DailyCounter
+		views, err := dc.GetDailyCounter(r.Context(), storage.DB, counter.TotalViews, req.From, req.To)
+		if err != nil {
+			log.Error("failed to get views history", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get views history"))
+			return
+		}
+
+		log.Info("views history gotten")
+
+		responseOK(w, r, log, views)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, log *slog.Logger, views []counter.DailyCounter) {
+	response, err := json.Marshal(Response{
+		Response: resp.OK(),
+		Views:    views,
+	})
+	if err != nil {
+		log.Error("failed to process response", sl.Err(err))
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to process response"))
+		return
+	}
+
+	render.Data(w, r, response)
+}
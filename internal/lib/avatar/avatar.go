@@ -0,0 +1,118 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"portal/internal/lib/imaging"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+	"portal/internal/structs/models"
+)
+
+// Sizes of square avatar thumbnails. image_path of the user contains the largest one
+var Sizes = []int{64, 128, 256}
+
+const (
+	dir       = "avatars"
+	extension = ".jpg"
+)
+
+// Name returns object name of the avatar variant. Version changes on each upload, so browsers don't show cached old avatar
+func Name(userID int, version int64, size int) string {
+	return fmt.Sprintf("%s/user%d_%d_%d%s", dir, userID, version, size, extension)
+}
+
+// VariantNames returns object names of all sizes by image_path of the user
+func VariantNames(imagePath string) []string {
+	imagePath = blob.ObjectName(imagePath)
+	suffix := "_" + strconv.Itoa(Sizes[len(Sizes)-1]) + extension
+	if !strings.HasPrefix(imagePath, dir+"/") || !strings.HasSuffix(imagePath, suffix) {
+		return nil
+	}
+
+	prefix := strings.TrimSuffix(imagePath, suffix)
+	names := make([]string, 0, len(Sizes))
+	for _, size := range Sizes {
+		names = append(names, prefix+"_"+strconv.Itoa(size)+extension)
+	}
+	return names
+}
+
+// Save uploads thumbnails of the image, sets image_path of the user and removes previous avatar.
+// Uploaded thumbnails are removed if the avatar is not saved. Returns image links by size
+func Save(ctx context.Context, log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore, userID int, img image.Image) (map[int]string, error) {
+	const op = "lib.avatar.Save"
+
+	version := time.Now().UnixNano()
+	links := make(map[int]string, len(Sizes))
+	uploaded := make([]string, 0, len(Sizes))
+	for _, size := range Sizes {
+		payload, err := imaging.EncodeJPEG(imaging.SquareThumbnail(img, size))
+		if err != nil {
+			removeObjects(ctx, log, blobStore, uploaded)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		name := Name(userID, version, size)
+		err = blobStore.Put(ctx, models.Image{
+			Payload:   bytes.NewReader(payload),
+			Name:      name,
+			Size:      int64(len(payload)),
+			Extension: "jpeg",
+		})
+		if err != nil {
+			removeObjects(ctx, log, blobStore, uploaded)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links[size] = blob.ImageLink(name)
+		uploaded = append(uploaded, name)
+	}
+
+	// Старый путь читается в том же запросе, что и заменяется: при параллельных загрузках
+	// каждая удаляет только тот аватар, который заменила сама
+	var u user.User
+	if err := u.ReplaceImagePath(ctx, storage.DB, userID, links[Sizes[len(Sizes)-1]]); err != nil {
+		removeObjects(ctx, log, blobStore, uploaded)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Старые файлы удаляются после обновления БД. Ошибка удаления не влияет на результат загрузки
+	removeObjects(ctx, log, blobStore, VariantNames(u.ImagePath))
+
+	return links, nil
+}
+
+// Delete removes avatar of the user. Files are removed after the DB update, failed ones are left for the blob gc
+func Delete(ctx context.Context, log *slog.Logger, storage *postgres.Storage, blobStore blob.BlobStore, userID int) error {
+	const op = "lib.avatar.Delete"
+
+	var u user.User
+	if err := u.ReplaceImagePath(ctx, storage.DB, userID, ""); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	removeObjects(ctx, log, blobStore, VariantNames(u.ImagePath))
+
+	return nil
+}
+
+// removeObjects deletes all the objects even if some of them fail. Errors are only logged:
+// objects without references in DB are removed later by the blob gc
+func removeObjects(ctx context.Context, log *slog.Logger, blobStore blob.BlobStore, names []string) {
+	// Удаление не должно прерываться вместе с запросом
+	ctx = context.WithoutCancel(ctx)
+	for _, name := range names {
+		if err := blobStore.Delete(ctx, name); err != nil {
+			log.Warn("failed to delete avatar object", slog.String("name", name), sl.Err(err))
+		}
+	}
+}
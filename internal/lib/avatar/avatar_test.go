@@ -0,0 +1,48 @@
+package avatar
+
+import (
+	"reflect"
+	"testing"
+
+	"portal/internal/storage/blob"
+)
+
+func TestVariantNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		imagePath string
+		want      []string
+	}{
+		{
+			name:      "largest size",
+			imagePath: Name(42, 1700000000, 256),
+			want: []string{
+				"avatars/user42_1700000000_64.jpg",
+				"avatars/user42_1700000000_128.jpg",
+				"avatars/user42_1700000000_256.jpg",
+			},
+		},
+		{
+			name:      "image link",
+			imagePath: blob.ImageLink(Name(42, 1700000000, 256)),
+			want: []string{
+				"avatars/user42_1700000000_64.jpg",
+				"avatars/user42_1700000000_128.jpg",
+				"avatars/user42_1700000000_256.jpg",
+			},
+		},
+		{name: "empty", imagePath: "", want: nil},
+		{name: "not largest size", imagePath: Name(42, 1700000000, 64), want: nil},
+		{name: "other dir", imagePath: "shop/user42_1700000000_256.jpg", want: nil},
+		// Путь задан не через Name, вариантов у него нет
+		{name: "legacy", imagePath: "avatars/user42.png", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VariantNames(tt.imagePath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VariantNames(%q) = %q, want %q", tt.imagePath, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package blobGC
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"portal/internal/lib/avatar"
+	"portal/internal/lib/logger/sl"
+	postImage "portal/internal/lib/post_image"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/media"
+)
+
+var ErrCollectInProgress = errors.New("blob gc is already in progress")
+
+// DefaultPrefixes are storage prefixes the portal uploads to. Objects outside them are never deleted.
+// Shop item photos are not here: item.photo_path is set by hand and may point anywhere, even to an URL
+var DefaultPrefixes = []string{"post_images/", "avatars/"}
+
+// Объекты моложе этого срока не удаляются: загрузка по подписанной ссылке может быть еще не завершена
+const DefaultGracePeriod = 24 * time.Hour
+
+// Report contains orphaned objects and rows referring to missing objects
+type Report struct {
+	StartDate  time.Time `json:"start_date"`
+	FinishDate time.Time `json:"finish_date"`
+	DryRun     bool      `json:"dry_run"`
+	Scanned    int       `json:"scanned"`
+	Referenced int       `json:"referenced"`
+	// Объекты без ссылок в БД: удалены или, в режиме dry run, будут удалены
+	Orphaned      []string `json:"orphaned"`
+	OrphanedBytes int64    `json:"orphaned_bytes"`
+	// Объекты без ссылок, которые моложе GracePeriod
+	InGracePeriod []string `json:"in_grace_period"`
+	Failed        []string `json:"failed"`
+	// Записи БД, файлы которых отсутствуют в хранилище. Не удаляются, требуют разбора
+	Dangling []media.Reference `json:"dangling"`
+}
+
+// Collector finds objects in the blob store which are not referred by post_image, "user" or item and deletes them
+type Collector struct {
+	Storage     *postgres.Storage
+	BlobStore   blob.BlobStore
+	Log         *slog.Logger
+	GracePeriod time.Duration
+	Prefixes    []string // По умолчанию DefaultPrefixes
+
+	running    sync.Mutex
+	mu         sync.RWMutex
+	lastReport *Report
+}
+
+// Run collects garbage every interval until ctx is done
+func (c *Collector) Run(ctx context.Context, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Collect(ctx, dryRun); err != nil && !errors.Is(err, ErrCollectInProgress) {
+				c.Log.Error("blob gc failed", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Collect runs one reconciliation. In dry run mode nothing is deleted.
+// Only one collection runs at a time, concurrent call returns ErrCollectInProgress
+func (c *Collector) Collect(ctx context.Context, dryRun bool) (Report, error) {
+	const op = "lib.blobGC.Collect"
+
+	if !c.running.TryLock() {
+		return Report{}, fmt.Errorf("%s: %w", op, ErrCollectInProgress)
+	}
+	defer c.running.Unlock()
+
+	report := Report{StartDate: time.Now(), DryRun: dryRun, Orphaned: []string{}, InGracePeriod: []string{}, Failed: []string{}, Dangling: []media.Reference{}}
+
+	prefixes := c.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = DefaultPrefixes
+	}
+	gracePeriod := c.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultGracePeriod
+	}
+
+	// Сначала читаем хранилище, потом БД: объект, загруженный между этими шагами, попадет в grace period, а не в удаление
+	objects, err := c.scan(ctx, prefixes)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var ref media.Reference
+	refs, err := ref.GetReferences(ctx, c.Storage.DB)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.reconcile(ctx, &report, objects, refs, prefixes, gracePeriod)
+	report.FinishDate = time.Now()
+
+	c.mu.Lock()
+	c.lastReport = &report
+	c.mu.Unlock()
+
+	c.Log.Info("blob gc finished",
+		slog.Bool("dry_run", dryRun),
+		slog.Int("scanned", report.Scanned),
+		slog.Int("referenced", report.Referenced),
+		slog.Int("orphaned", len(report.Orphaned)),
+		slog.Int64("orphaned_bytes", report.OrphanedBytes),
+		slog.Int("in_grace_period", len(report.InGracePeriod)),
+		slog.Int("failed", len(report.Failed)),
+		slog.Int("dangling", len(report.Dangling)),
+	)
+
+	return report, nil
+}
+
+// LastReport returns report of the last finished collection, false if there was no collection yet
+func (c *Collector) LastReport() (Report, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.lastReport == nil {
+		return Report{}, false
+	}
+	return *c.lastReport, true
+}
+
+// scan returns objects stored under the prefixes by name
+func (c *Collector) scan(ctx context.Context, prefixes []string) (map[string]blob.ObjectInfo, error) {
+	objects := map[string]blob.ObjectInfo{}
+	for _, prefix := range prefixes {
+		infos, err := c.BlobStore.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			objects[info.Name] = info
+		}
+	}
+	return objects, nil
+}
+
+// reconcile matches stored objects against references from the database, fills the report
+// and, unless report.DryRun is set, deletes orphaned objects older than gracePeriod
+func (c *Collector) reconcile(ctx context.Context, report *Report, objects map[string]blob.ObjectInfo, refs []media.Reference, prefixes []string, gracePeriod time.Duration) {
+	report.Scanned = len(objects)
+
+	referenced := map[string]bool{}
+	for _, r := range refs {
+		names := referencedNames(r)
+		for _, name := range names {
+			referenced[name] = true
+		}
+
+		// Запись ссылается на файл в управляемом префиксе, но файла нет
+		if primary := names[0]; hasPrefix(primary, prefixes) {
+			if _, ok := objects[primary]; !ok {
+				report.Dangling = append(report.Dangling, r)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(-gracePeriod)
+	for name, info := range objects {
+		if referenced[name] {
+			report.Referenced++
+			continue
+		}
+		if info.LastModified.After(deadline) {
+			report.InGracePeriod = append(report.InGracePeriod, name)
+			continue
+		}
+
+		if !report.DryRun {
+			if err := c.BlobStore.Delete(ctx, name); err != nil {
+				c.Log.Warn("failed to delete orphaned object", slog.String("name", name), sl.Err(err))
+				report.Failed = append(report.Failed, name)
+				continue
+			}
+		}
+		report.Orphaned = append(report.Orphaned, name)
+		report.OrphanedBytes += info.Size
+	}
+}
+
+// referencedNames returns object names used by the row. The first name is the object the row points to,
+// the rest are generated variants
+func referencedNames(r media.Reference) []string {
+	name := blob.ObjectName(r.Path)
+
+	if r.Table == media.TableUser {
+		if variants := avatar.VariantNames(r.Path); len(variants) != 0 {
+			return append([]string{name}, variants...)
+		}
+	}
+
+	return postImage.ObjectNames(name)
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package blobGC
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"portal/internal/storage/blob"
+	localStorage "portal/internal/storage/local"
+	"portal/internal/storage/postgres/entities/media"
+	"portal/internal/structs/models"
+)
+
+func TestReconcile(t *testing.T) {
+	const gracePeriod = time.Hour
+
+	// Объекты в хранилище и признак того, что они загружены давно
+	objects := map[string]bool{
+		"post_images/a.png":           true,
+		"post_images/a.png_thumbnail": true,
+		"post_images/a.png_preview":   true,
+		"post_images/a.png_full":      true,
+		"post_images/orphan.png":      true,
+		"post_images/fresh.png":       false,
+		"avatars/user1_10_64.jpg":     true,
+		"avatars/user1_10_128.jpg":    true,
+		"avatars/user1_10_256.jpg":    true,
+		"avatars/user2_5_256.jpg":     true,
+		"shop/item.png":               true,
+	}
+	refs := []media.Reference{
+		{Table: media.TablePostImage, ID: 1, Path: "https://portal.local/api/image?name=post_images/a.png"},
+		{Table: media.TablePostImage, ID: 2, Path: "https://portal.local/api/image?name=post_images/missing.png"},
+		{Table: media.TableUser, ID: 1, Path: blob.ImageLink("avatars/user1_10_256.jpg")},
+		// Фото товара может ссылаться куда угодно, такие записи не считаются битыми
+		{Table: media.TableItem, ID: 1, Path: "https://example.com/item.png"},
+	}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+	}{
+		{name: "dry run", dryRun: true},
+		{name: "delete", dryRun: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			root := t.TempDir()
+			store, err := localStorage.New(root)
+			if err != nil {
+				t.Fatalf("localStorage.New() error = %v", err)
+			}
+
+			old := time.Now().Add(-2 * gracePeriod)
+			for name, isOld := range objects {
+				img := models.Image{Name: name, Payload: strings.NewReader("data"), Size: 4}
+				if err := store.Put(ctx, img); err != nil {
+					t.Fatalf("Put(%q) error = %v", name, err)
+				}
+				if isOld {
+					if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(name)), old, old); err != nil {
+						t.Fatalf("Chtimes(%q) error = %v", name, err)
+					}
+				}
+			}
+
+			c := &Collector{BlobStore: store, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			report := Report{DryRun: tt.dryRun, Orphaned: []string{}, InGracePeriod: []string{}, Failed: []string{}, Dangling: []media.Reference{}}
+
+			scanned, err := c.scan(ctx, DefaultPrefixes)
+			if err != nil {
+				t.Fatalf("scan() error = %v", err)
+			}
+			c.reconcile(ctx, &report, scanned, refs, DefaultPrefixes, gracePeriod)
+
+			if report.Scanned != 10 {
+				t.Errorf("Scanned = %d, want 10", report.Scanned)
+			}
+			if report.Referenced != 7 {
+				t.Errorf("Referenced = %d, want 7", report.Referenced)
+			}
+
+			wantOrphaned := []string{"avatars/user2_5_256.jpg", "post_images/orphan.png"}
+			sort.Strings(report.Orphaned)
+			if !reflect.DeepEqual(report.Orphaned, wantOrphaned) {
+				t.Errorf("Orphaned = %q, want %q", report.Orphaned, wantOrphaned)
+			}
+			if report.OrphanedBytes != 8 {
+				t.Errorf("OrphanedBytes = %d, want 8", report.OrphanedBytes)
+			}
+			if want := []string{"post_images/fresh.png"}; !reflect.DeepEqual(report.InGracePeriod, want) {
+				t.Errorf("InGracePeriod = %q, want %q", report.InGracePeriod, want)
+			}
+			if len(report.Failed) != 0 {
+				t.Errorf("Failed = %q, want empty", report.Failed)
+			}
+			if want := []media.Reference{refs[1]}; !reflect.DeepEqual(report.Dangling, want) {
+				t.Errorf("Dangling = %+v, want %+v", report.Dangling, want)
+			}
+
+			// Удаляются только осиротевшие объекты и только не в режиме dry run
+			for name := range objects {
+				_, err := store.Stat(ctx, name)
+				orphaned := name == "avatars/user2_5_256.jpg" || name == "post_images/orphan.png"
+				if exists, wantExists := err == nil, tt.dryRun || !orphaned; exists != wantExists {
+					t.Errorf("object %q exists = %v, want %v (stat error = %v)", name, exists, wantExists, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// Значения тега Orientation (0x0112) из EXIF
+const (
+	orientationNormal        = 1
+	orientationFlipH         = 2
+	orientationRotate180     = 3
+	orientationFlipV         = 4
+	orientationTranspose     = 5
+	orientationRotate90      = 6
+	orientationTransverse    = 7
+	orientationRotate270     = 8
+	exifOrientationTag       = 0x0112
+	jpegMarkerAPP1           = 0xE1
+	jpegMarkerStartOfScan    = 0xDA
+	exifHeader               = "Exif\x00\x00"
+	tiffEntrySize            = 12
+	tiffEntriesCountSize     = 2
+	tiffFirstIFDOffsetOffset = 4
+)
+
+// jpegOrientation reads EXIF orientation from jpeg data. Returns orientationNormal if it is not set or broken
+func jpegOrientation(data []byte) int {
+	// Пропускаем SOI и идем по сегментам до начала данных изображения
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return orientationNormal
+		}
+		marker := data[i+1]
+		if marker == jpegMarkerStartOfScan {
+			return orientationNormal
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return orientationNormal
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == jpegMarkerAPP1 && bytes.HasPrefix(segment, []byte(exifHeader)) {
+			return tiffOrientation(segment[len(exifHeader):])
+		}
+		i += 2 + length
+	}
+	return orientationNormal
+}
+
+// tiffOrientation reads orientation tag from the first IFD of TIFF structure inside EXIF segment
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientationNormal
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationNormal
+	}
+
+	offset := int(order.Uint32(tiff[tiffFirstIFDOffsetOffset:]))
+	if offset < 0 || offset+tiffEntriesCountSize > len(tiff) {
+		return orientationNormal
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	entries := tiff[offset+tiffEntriesCountSize:]
+	for n := 0; n < count && (n+1)*tiffEntrySize <= len(entries); n++ {
+		entry := entries[n*tiffEntrySize:]
+		if order.Uint16(entry) != exifOrientationTag {
+			continue
+		}
+		// Значение типа SHORT хранится в первых двух байтах поля значения
+		orientation := int(order.Uint16(entry[8:]))
+		if orientation < orientationNormal || orientation > orientationRotate270 {
+			return orientationNormal
+		}
+		return orientation
+	}
+	return orientationNormal
+}
+
+// applyOrientation returns the image as it should be displayed according to EXIF orientation
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation == orientationNormal {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dstW, dstH := w, h
+	if orientation >= orientationTranspose {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case orientationFlipH:
+				dx, dy = w-1-x, y
+			case orientationRotate180:
+				dx, dy = w-1-x, h-1-y
+			case orientationFlipV:
+				dx, dy = x, h-1-y
+			case orientationTranspose:
+				dx, dy = y, x
+			case orientationRotate90:
+				dx, dy = h-1-y, x
+			case orientationTransverse:
+				dx, dy = h-1-y, w-1-x
+			case orientationRotate270:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	return dst
+}
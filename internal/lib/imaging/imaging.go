@@ -0,0 +1,124 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Регистрация декодера webp для image.Decode
+)
+
+const (
+	jpegQuality = 85
+	// Ограничение на размер изображения в пикселях, чтобы небольшой файл не занял всю память при декодировании
+	maxPixels = 50_000_000
+)
+
+var ErrImageTooLarge = errors.New("image resolution out of limit")
+
+// Decode sniffs the format by magic bytes and decodes jpeg, png or webp image.
+// Jpeg image is rotated according to EXIF orientation, because EXIF is lost on re-encoding
+func Decode(data []byte) (image.Image, string, error) {
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image config: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if format == FormatJPEG {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	return img, format, nil
+}
+
+// OutputFormat returns format for re-encoding: png stays png, webp becomes png only if it has transparency
+func OutputFormat(srcFormat string, img image.Image) string {
+	switch srcFormat {
+	case FormatPNG:
+		return FormatPNG
+	case FormatWebP:
+		if o, ok := img.(interface{ Opaque() bool }); ok && !o.Opaque() {
+			return FormatPNG
+		}
+	}
+	return FormatJPEG
+}
+
+// Encode encodes image to jpeg or png. Metadata of the source (EXIF and others) is not written
+func Encode(img image.Image, format string) ([]byte, error) {
+	if format == FormatPNG {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return EncodeJPEG(img)
+}
+
+// Fit scales the image down to fit into maxSide x maxSide keeping proportions. Smaller images are not scaled up
+func Fit(src image.Image, maxSide int) image.Image {
+	b := src.Bounds()
+	if b.Dx() <= maxSide && b.Dy() <= maxSide {
+		return src
+	}
+
+	w, h := maxSide, b.Dy()*maxSide/b.Dx()
+	if b.Dy() > b.Dx() {
+		w, h = b.Dx()*maxSide/b.Dy(), maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	return dst
+}
+
+// SquareThumbnail crops the center square of the image and scales it to size x size
+func SquareThumbnail(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	// Прозрачные области png заливаются белым, т.к. jpeg не поддерживает прозрачность
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+	return dst
+}
+
+// EncodeJPEG encodes image to jpeg. Transparent areas become white
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	if o, ok := img.(interface{ Opaque() bool }); !ok || !o.Opaque() {
+		b := img.Bounds()
+		flat := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, b.Min, draw.Over)
+		img = flat
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,34 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Форматы изображений, которые принимаются при загрузке
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+var ErrFormatNotAllowed = errors.New("image format is not allowed")
+
+// Sniff detects image format by magic bytes. 12 bytes of the header are enough
+func Sniff(header []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, nil
+	case len(header) >= 12 && bytes.Equal(header[:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return FormatWebP, nil
+	default:
+		return "", ErrFormatNotAllowed
+	}
+}
+
+// ContentType returns MIME type of the format
+func ContentType(format string) string {
+	return "image/" + format
+}
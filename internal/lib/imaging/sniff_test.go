@@ -0,0 +1,37 @@
+package imaging
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []byte
+		want    string
+		wantErr error
+	}{
+		{name: "jpeg", header: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01}, want: FormatJPEG},
+		{name: "png", header: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d"), want: FormatPNG},
+		{name: "webp", header: []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), want: FormatWebP},
+		{name: "empty", header: nil, wantErr: ErrFormatNotAllowed},
+		{name: "short jpeg", header: []byte{0xFF, 0xD8}, wantErr: ErrFormatNotAllowed},
+		{name: "gif", header: []byte("GIF89a\x01\x00\x01\x00\x00\x00"), wantErr: ErrFormatNotAllowed},
+		{name: "riff not webp", header: []byte("RIFF\x24\x00\x00\x00WAVEfmt "), wantErr: ErrFormatNotAllowed},
+		{name: "short riff", header: []byte("RIFF\x24\x00\x00\x00WEB"), wantErr: ErrFormatNotAllowed},
+		{name: "svg", header: []byte("<svg xmlns="), wantErr: ErrFormatNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff(tt.header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sniff() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Sniff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+)
+
+var MaxSize = int64(9437184) // 9 MB
+
+var ErrSizeOutOfLimit = errors.New("image size out of limit")
+
+// CheckUpload checks size of uploaded image and sniffs its format by magic bytes. Extension of the file is not trusted
+func CheckUpload(hdr *multipart.FileHeader) (string, error) {
+	if hdr.Size > MaxSize {
+		return "", ErrSizeOutOfLimit
+	}
+
+	src, err := hdr.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open image: %w", err)
+	}
+	defer src.Close()
+
+	header := make([]byte, 12)
+	n, err := io.ReadFull(src, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", ErrFormatNotAllowed
+	}
+
+	return Sniff(header[:n])
+}
+
+// ReadUpload reads uploaded image with size limit
+func ReadUpload(hdr *multipart.FileHeader) ([]byte, error) {
+	if hdr.Size > MaxSize {
+		return nil, ErrSizeOutOfLimit
+	}
+
+	src, err := hdr.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > MaxSize {
+		return nil, ErrSizeOutOfLimit
+	}
+
+	return data, nil
+}
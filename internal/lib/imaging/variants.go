@@ -0,0 +1,61 @@
+package imaging
+
+// Variant is a resized copy of the uploaded image stored next to the original
+type Variant struct {
+	Name    string
+	MaxSide int
+}
+
+var Variants = []Variant{
+	{Name: "thumbnail", MaxSide: 320},
+	{Name: "preview", MaxSide: 1024},
+	{Name: "full", MaxSide: 2048},
+}
+
+// FindVariant returns variant by name
+func FindVariant(name string) (Variant, bool) {
+	for _, v := range Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+// VariantName returns object name of the variant of the original object
+func VariantName(name string, v Variant) string {
+	return name + "_" + v.Name
+}
+
+// Processed is re-encoded original and its variants ready for upload
+type Processed struct {
+	ContentType string
+	Original    []byte
+	Variants    map[string][]byte // Ключ - имя варианта
+}
+
+// Process sniffs, decodes and re-encodes uploaded image without metadata and generates all variants
+func Process(data []byte) (Processed, error) {
+	img, srcFormat, err := Decode(data)
+	if err != nil {
+		return Processed{}, err
+	}
+
+	format := OutputFormat(srcFormat, img)
+	p := Processed{ContentType: ContentType(format), Variants: make(map[string][]byte, len(Variants))}
+
+	p.Original, err = Encode(img, format)
+	if err != nil {
+		return Processed{}, err
+	}
+
+	for _, v := range Variants {
+		payload, err := Encode(Fit(img, v.MaxSide), format)
+		if err != nil {
+			return Processed{}, err
+		}
+		p.Variants[v.Name] = payload
+	}
+
+	return p, nil
+}
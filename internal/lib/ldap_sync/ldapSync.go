@@ -0,0 +1,204 @@
+package ldapSync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"portal/internal/lib/logger/sl"
+	ldapServer "portal/internal/storage/ldap"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/user"
+)
+
+var ErrSyncInProgress = errors.New("ldap sync is already in progress")
+
+const defaultPagingSize = 500
+
+// Report contains usernames changed by the sync
+type Report struct {
+	StartDate   time.Time `json:"start_date"`
+	FinishDate  time.Time `json:"finish_date"`
+	Total       int       `json:"total"`
+	Created     []string  `json:"created"`
+	Updated     []string  `json:"updated"`
+	Deactivated []string  `json:"deactivated"`
+	Failed      []string  `json:"failed"`
+	// Количество пользователей, у которых изменился руководитель
+	ChiefsUpdated int `json:"chiefs_updated"`
+}
+
+// Syncer copies user accounts from the directory into the "user" table
+type Syncer struct {
+	Storage     *postgres.Storage
+	LDAPServer  *ldapServer.LDAPServer
+	Log         *slog.Logger
+	DefaultRole int    // Роль новых пользователей до первого входа, при входе уточняется по группам LDAP
+	PagingSize  uint32 // Размер страницы поиска в каталоге
+
+	running    sync.Mutex
+	mu         sync.RWMutex
+	lastReport *Report
+}
+
+// Run syncs users every interval until ctx is done
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sync(ctx); err != nil && !errors.Is(err, ErrSyncInProgress) {
+				s.Log.Error("ldap sync failed", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Sync runs one sync. Only one sync runs at a time, concurrent call returns ErrSyncInProgress
+func (s *Syncer) Sync(ctx context.Context) (Report, error) {
+	const op = "lib.ldapSync.Sync"
+
+	if !s.running.TryLock() {
+		return Report{}, fmt.Errorf("%s: %w", op, ErrSyncInProgress)
+	}
+	defer s.running.Unlock()
+
+	return s.sync(ctx)
+}
+
+// Start runs one sync in background and returns immediately, the result is available by LastReport.
+// Returns ErrSyncInProgress if sync is already running
+func (s *Syncer) Start(ctx context.Context) error {
+	const op = "lib.ldapSync.Start"
+
+	if !s.running.TryLock() {
+		return fmt.Errorf("%s: %w", op, ErrSyncInProgress)
+	}
+
+	go func() {
+		defer s.running.Unlock()
+
+		if _, err := s.sync(ctx); err != nil {
+			s.Log.Error("ldap sync failed", sl.Err(err))
+		}
+	}()
+
+	return nil
+}
+
+// Wait blocks until the running sync, started by Run, Sync or Start, is finished
+func (s *Syncer) Wait() {
+	s.running.Lock()
+	defer s.running.Unlock()
+}
+
+// sync must be called with running locked
+func (s *Syncer) sync(ctx context.Context) (Report, error) {
+	const op = "lib.ldapSync.sync"
+
+	report := Report{StartDate: time.Now(), Created: []string{}, Updated: []string{}, Deactivated: []string{}, Failed: []string{}}
+
+	pagingSize := s.PagingSize
+	if pagingSize == 0 {
+		pagingSize = defaultPagingSize
+	}
+	dirUsers, err := s.LDAPServer.ListUsers(pagingSize)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// Пустой каталог означает ошибку поиска (например, неверный BaseDN), иначе будут деактивированы все пользователи
+	if len(dirUsers) == 0 {
+		return Report{}, fmt.Errorf("%s: no users found in directory", op)
+	}
+
+	usernames := make([]string, 0, len(dirUsers))
+	for _, du := range dirUsers {
+		usernames = append(usernames, du.Username)
+
+		u := user.User{
+			Username:   du.Username,
+			FullName:   du.Name,
+			Position:   du.Title,
+			Department: du.Department,
+			Mobile:     du.Mobile,
+			Mail:       du.Mail,
+			Chief:      du.Chief,
+			IsActive:   du.IsActive,
+		}
+		result, err := u.SyncUser(ctx, s.Storage.DB, s.DefaultRole)
+		if err != nil {
+			s.Log.Warn("failed to sync user", slog.String("username", du.Username), sl.Err(err))
+			report.Failed = append(report.Failed, du.Username)
+			continue
+		}
+
+		switch result {
+		case user.SyncCreated:
+			report.Created = append(report.Created, du.Username)
+		case user.SyncUpdated:
+			report.Updated = append(report.Updated, du.Username)
+		case user.SyncDeactivated:
+			report.Deactivated = append(report.Deactivated, du.Username)
+		}
+	}
+	report.Total = len(dirUsers)
+
+	// Связываем пользователей с руководителями по DN из атрибута manager после создания всех пользователей
+	usernameByDN := make(map[string]string, len(dirUsers))
+	for _, du := range dirUsers {
+		usernameByDN[strings.ToLower(du.DN)] = du.Username
+	}
+	chiefUsernames := make([]string, 0, len(dirUsers))
+	for _, du := range dirUsers {
+		chiefUsernames = append(chiefUsernames, usernameByDN[strings.ToLower(du.ManagerDN)])
+	}
+	var chiefs user.User
+	report.ChiefsUpdated, err = chiefs.SetChiefs(ctx, s.Storage.DB, usernames, chiefUsernames)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Учетные записи, удаленные из каталога, тоже деактивируются
+	var u user.User
+	missing, err := u.DeactivateMissingUsers(ctx, s.Storage.DB, usernames)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+	report.Deactivated = append(report.Deactivated, missing...)
+
+	report.FinishDate = time.Now()
+
+	s.mu.Lock()
+	s.lastReport = &report
+	s.mu.Unlock()
+
+	s.Log.Info("ldap sync finished",
+		slog.Int("total", report.Total),
+		slog.Int("created", len(report.Created)),
+		slog.Int("updated", len(report.Updated)),
+		slog.Int("deactivated", len(report.Deactivated)),
+		slog.Int("failed", len(report.Failed)),
+		slog.Int("chiefs_updated", report.ChiefsUpdated),
+	)
+
+	return report, nil
+}
+
+// LastReport returns report of the last finished sync, false if there was no sync yet
+func (s *Syncer) LastReport() (Report, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.lastReport == nil {
+		return Report{}, false
+	}
+	return *s.lastReport, true
+}
@@ -0,0 +1,74 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	resp "portal/internal/lib/api/response"
+
+	"github.com/go-chi/render"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ClientCredentialPrefix marks token credential of a service principal, so it never matches a username
+const ClientCredentialPrefix = "client:"
+
+// GenerateClientSecret returns new random client secret and its hash for storing in DB
+func GenerateClientSecret() (secret, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(raw)
+
+	bHash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, string(bHash), nil
+}
+
+// CompareClientSecret checks the secret matches stored hash
+func CompareClientSecret(hash, secret string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret))
+}
+
+// ClientCredentials manages client_credentials grant type requests (RFC 6749 4.4).
+// Client authenticates with HTTP Basic or with client_id and client_secret form fields
+func (bs *BearerServer) ClientCredentials(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(400)
+		render.JSON(w, r, resp.Error("failed to parse request: "+err.Error()))
+		return
+	}
+
+	if GrantType(r.Form.Get("grant_type")) != ClientCredentialsGrant {
+		w.WriteHeader(400)
+		render.JSON(w, r, resp.Error("Invalid grant_type"))
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.Form.Get("client_id"), r.Form.Get("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="portal"`)
+		w.WriteHeader(401)
+		render.JSON(w, r, resp.Error("client credentials are required"))
+		return
+	}
+
+	response, _, statusCode := bs.generateTokenResponse(ClientCredentialsGrant, clientID, clientSecret, strings.TrimSpace(r.Form.Get("scope")), "", r)
+	if statusCode != 200 {
+		w.WriteHeader(statusCode)
+		render.JSON(w, r, resp.Error(response))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, LoginResponse{Response: resp.OK(), TokenResponse: *response.(*TokenResponse)})
+}
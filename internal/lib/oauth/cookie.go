@@ -0,0 +1,122 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const cookieTTL = 2160 * time.Hour // Время зачитски куки из браузера (ttl токена находитися в bs.TokenTTL)
+
+const deviceCookieName = "device_id"
+
+// CookieOptions defines attributes of auth cookies
+type CookieOptions struct {
+	Secure   bool
+	SameSite http.SameSite
+	Path     string
+	Domain   string
+}
+
+// DefaultCookieOptions are used by NewBearerServer, cookies are sent only over https
+func DefaultCookieOptions() CookieOptions {
+	return CookieOptions{Secure: true, SameSite: http.SameSiteLaxMode, Path: "/"}
+}
+
+// ParseSameSite converts config value (strict, lax, none) to http.SameSite. Empty value means lax
+func ParseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+func (o CookieOptions) cookie(name, value string, httpOnly bool) *http.Cookie {
+	// Без Path браузер привязывает cookie к пути запроса /api/login
+	path := o.Path
+	if path == "" {
+		path = "/"
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   o.Domain,
+		Expires:  time.Now().Add(cookieTTL),
+		Secure:   o.Secure,
+		HttpOnly: httpOnly,
+		SameSite: o.SameSite,
+	}
+}
+
+// setAuthCookies sets access_token, refresh_token, role and new csrf_token cookies.
+// Response without refresh token (parallel refresh) keeps refresh_token cookie unchanged
+func (bs *BearerServer) setAuthCookies(w http.ResponseWriter, response *TokenResponse, role int) error {
+	// csrf токен выдается заново при каждой смене токенов: при входе и обновлении
+	if err := bs.setCSRFCookie(w); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, bs.Cookie.cookie("access_token", response.Token, true))
+	if response.RefreshToken != "" {
+		http.SetCookie(w, bs.Cookie.cookie("refresh_token", response.RefreshToken, true))
+	}
+	http.SetCookie(w, bs.Cookie.cookie("role", strconv.Itoa(role), true))
+
+	return nil
+}
+
+// setCSRFCookie sets new csrf_token cookie. Cookie is readable by frontend scripts,
+// its value must be sent back in X-CSRF-Token header of state-changing requests
+func (bs *BearerServer) setCSRFCookie(w http.ResponseWriter) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, bs.Cookie.cookie(csrfCookieName, hex.EncodeToString(raw), false))
+
+	return nil
+}
+
+// setDeviceCookie sets device_id cookie identifying the browser session.
+// The cookie is kept on logout, so the next login replaces the session of the device
+func (bs *BearerServer) setDeviceCookie(w http.ResponseWriter, deviceID string) {
+	http.SetCookie(w, bs.Cookie.cookie(deviceCookieName, deviceID, true))
+}
+
+// requestDeviceID returns device ID sent by the client in the request body or in device_id cookie.
+// New device ID is generated if there is no valid one
+func requestDeviceID(fromBody string, r *http.Request) string {
+	deviceID := fromBody
+	if deviceID == "" {
+		if cookie, err := r.Cookie(deviceCookieName); err == nil {
+			deviceID = cookie.Value
+		}
+	}
+
+	if id, err := uuid.FromString(deviceID); err == nil {
+		return id.String()
+	}
+
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+// clearAuthCookies makes browser delete auth cookies
+func (bs *BearerServer) clearAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{"access_token", "refresh_token", "role", csrfCookieName} {
+		c := bs.Cookie.cookie(name, "", name != csrfCookieName)
+		c.Expires = time.Unix(0, 0)
+		c.MaxAge = -1
+		http.SetCookie(w, c)
+	}
+}
@@ -0,0 +1,62 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+
+	resp "portal/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// CSRF is double-submit cookie middleware for go-chi. State-changing requests authorized by cookie
+// must contain X-CSRF-Token header equal to the csrf_token cookie.
+// Requests with Authorization header are not checked: browser doesn't add the header to cross-site requests
+func CSRF(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "lib.oauth.CSRF"
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := log.With(
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			cookie, err := r.Cookie(csrfCookieName)
+			if err != nil || cookie.Value == "" {
+				log.Error("csrf cookie not found")
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, resp.Error("csrf token mismatch"))
+				return
+			}
+
+			header := r.Header.Get(csrfHeaderName)
+			if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+				log.Error("csrf token mismatch")
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, resp.Error("csrf token mismatch"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
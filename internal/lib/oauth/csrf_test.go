@@ -0,0 +1,96 @@
+package oauth
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	tests := []struct {
+		name          string
+		method        string
+		cookie        string
+		header        string
+		authorization string
+		emptyCookie   bool
+		wantStatus    int
+	}{
+		{name: "get without token", method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "head without token", method: http.MethodHead, wantStatus: http.StatusOK},
+		{name: "options without token", method: http.MethodOptions, wantStatus: http.StatusOK},
+		{name: "post with matching token", method: http.MethodPost, cookie: token, header: token, wantStatus: http.StatusOK},
+		{name: "delete with matching token", method: http.MethodDelete, cookie: token, header: token, wantStatus: http.StatusOK},
+		{name: "post without cookie", method: http.MethodPost, header: token, wantStatus: http.StatusForbidden},
+		{name: "post without header", method: http.MethodPost, cookie: token, wantStatus: http.StatusForbidden},
+		{name: "put with other token", method: http.MethodPut, cookie: token, header: "fedcba9876543210", wantStatus: http.StatusForbidden},
+		{name: "patch with token prefix", method: http.MethodPatch, cookie: token, header: token[:8], wantStatus: http.StatusForbidden},
+		// Пустая cookie не должна совпадать с отсутствующим заголовком
+		{name: "post with empty cookie", method: http.MethodPost, emptyCookie: true, wantStatus: http.StatusForbidden},
+		{name: "post with authorization header", method: http.MethodPost, authorization: "Bearer token", wantStatus: http.StatusOK},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := CSRF(log)(next)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/posts", nil)
+			if tt.cookie != "" || tt.emptyCookie {
+				r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				r.Header.Set(csrfHeaderName, tt.header)
+			}
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSetCSRFCookie(t *testing.T) {
+	bs := NewBearerServer("secret", 0, nil, nil)
+
+	first := httptest.NewRecorder()
+	if err := bs.setCSRFCookie(first); err != nil {
+		t.Fatalf("setCSRFCookie() error = %v", err)
+	}
+	second := httptest.NewRecorder()
+	if err := bs.setCSRFCookie(second); err != nil {
+		t.Fatalf("setCSRFCookie() error = %v", err)
+	}
+
+	firstCookies, secondCookies := first.Result().Cookies(), second.Result().Cookies()
+	if len(firstCookies) != 1 || len(secondCookies) != 1 {
+		t.Fatalf("cookies = %d and %d, want 1", len(firstCookies), len(secondCookies))
+	}
+	cookie := firstCookies[0]
+	if cookie.Name != csrfCookieName {
+		t.Errorf("cookie name = %q, want %q", cookie.Name, csrfCookieName)
+	}
+	// Фронтенд читает значение cookie и отправляет его в заголовке
+	if cookie.HttpOnly {
+		t.Error("csrf cookie is HttpOnly")
+	}
+	if len(cookie.Value) != 64 {
+		t.Errorf("csrf token length = %d, want 64", len(cookie.Value))
+	}
+	if cookie.Value == secondCookies[0].Value {
+		t.Error("csrf token is not random")
+	}
+}
@@ -0,0 +1,223 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+// JWTKey is a key used to sign or verify tokens. ID is written to the kid header
+type JWTKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{} // nil for the keys which are only used to verify old tokens
+	VerifyKey interface{}
+}
+
+// NewHS256Key creates symmetric key for HS256 signing
+func NewHS256Key(kid string, secret []byte) JWTKey {
+	return JWTKey{ID: kid, Method: jwt.SigningMethodHS256, SignKey: secret, VerifyKey: secret}
+}
+
+// LoadJWTKey loads key of the alg type. For HS256 secret is used, for EdDSA and RS256 PEM files are read.
+// Empty privatePath makes verify-only key (e.g. previous key during rotation)
+func LoadJWTKey(kid, alg, privatePath, publicPath string, secret []byte) (JWTKey, error) {
+	const op = "lib.oauth.LoadJWTKey"
+
+	if alg == "" || alg == AlgHS256 {
+		if len(secret) == 0 {
+			return JWTKey{}, fmt.Errorf("%s: empty HS256 secret", op)
+		}
+		return NewHS256Key(kid, secret), nil
+	}
+
+	key := JWTKey{ID: kid}
+
+	var rawPrivate, rawPublic []byte
+	var err error
+	if privatePath != "" {
+		if rawPrivate, err = os.ReadFile(privatePath); err != nil {
+			return JWTKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if rawPublic, err = os.ReadFile(publicPath); err != nil {
+		return JWTKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch alg {
+	case AlgEdDSA:
+		key.Method = jwt.SigningMethodEdDSA
+		if rawPrivate != nil {
+			if key.SignKey, err = jwt.ParseEdPrivateKeyFromPEM(rawPrivate); err != nil {
+				return JWTKey{}, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if key.VerifyKey, err = jwt.ParseEdPublicKeyFromPEM(rawPublic); err != nil {
+			return JWTKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+	case AlgRS256:
+		key.Method = jwt.SigningMethodRS256
+		if rawPrivate != nil {
+			if key.SignKey, err = jwt.ParseRSAPrivateKeyFromPEM(rawPrivate); err != nil {
+				return JWTKey{}, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if key.VerifyKey, err = jwt.ParseRSAPublicKeyFromPEM(rawPublic); err != nil {
+			return JWTKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+	default:
+		return JWTKey{}, fmt.Errorf("%s: unsupported algorithm %s", op, alg)
+	}
+
+	return key, nil
+}
+
+// jwtPayload covers json fields of both Token and RefreshToken
+type jwtPayload struct {
+	ID             string         `json:"id_token"`
+	RefreshTokenID string         `json:"id_refresh_token,omitempty"`
+	SessionID      string         `json:"session_id"`
+	CreationDate   time.Time      `json:"date"`
+	ExpiresIn      time.Duration  `json:"expires_in,omitempty"`
+	Credential     string         `json:"credential"`
+	Scope          int            `json:"scope"`
+	Claims         map[string]int `json:"claims,omitempty"`
+	Type           string         `json:"typ"`
+}
+
+// jwtLeeway allows small clock skew between servers
+const jwtLeeway = 30 * time.Second
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	UserID    int    `json:"user_id,omitempty"`
+	Scope     int    `json:"scope"`
+	TokenID   string `json:"tid,omitempty"` // Только в refresh токене: ID связанного access токена
+	SessionID string `json:"sid,omitempty"`
+	Type      string `json:"typ"`
+}
+
+// JWTTokenSecureFormatter signs tokens as JWT. Tokens are signed by the active key
+// and verified by any known key found by the kid header, so keys can be rotated without logging everyone out
+type JWTTokenSecureFormatter struct {
+	issuer string
+	active JWTKey
+	keys   map[string]JWTKey
+}
+
+func NewJWTTokenSecurityProvider(issuer string, active JWTKey, previous ...JWTKey) *JWTTokenSecureFormatter {
+	sc := &JWTTokenSecureFormatter{issuer: issuer, active: active, keys: make(map[string]JWTKey, len(previous)+1)}
+	for _, key := range previous {
+		sc.keys[key.ID] = key
+	}
+	sc.keys[active.ID] = active
+	return sc
+}
+
+func (sc *JWTTokenSecureFormatter) CryptToken(source []byte) ([]byte, error) {
+	var p jwtPayload
+	if err := json.Unmarshal(source, &p); err != nil {
+		return nil, err
+	}
+
+	claims := jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   sc.issuer,
+			Subject:  p.Credential,
+			ID:       p.ID,
+			IssuedAt: jwt.NewNumericDate(p.CreationDate),
+		},
+		UserID:    p.Claims["user_id"],
+		Scope:     p.Scope,
+		SessionID: p.SessionID,
+		Type:      p.Type,
+	}
+	if p.RefreshTokenID != "" {
+		claims.ID = p.RefreshTokenID
+		claims.TokenID = p.ID
+	}
+	if p.ExpiresIn == 0 {
+		return nil, errors.New("token without expiration")
+	}
+	claims.ExpiresAt = jwt.NewNumericDate(p.CreationDate.Add(p.ExpiresIn))
+
+	token := jwt.NewWithClaims(sc.active.Method, claims)
+	token.Header["kid"] = sc.active.ID
+
+	signed, err := token.SignedString(sc.active.SignKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(signed), nil
+}
+
+func (sc *JWTTokenSecureFormatter) DecryptToken(source []byte) ([]byte, error) {
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(string(source), &claims, sc.keyFunc, jwt.WithExpirationRequired(), jwt.WithIssuedAt(), jwt.WithLeeway(jwtLeeway))
+	if err != nil {
+		// По истекшему access токену вызывающая сторона выполняет обновление через refresh токен
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, errors.New("Invalid token: " + err.Error())
+	}
+	if claims.Issuer != sc.issuer {
+		return nil, errors.New("Invalid token: wrong issuer")
+	}
+
+	p := jwtPayload{
+		ID:         claims.ID,
+		SessionID:  claims.SessionID,
+		Credential: claims.Subject,
+		Scope:      claims.Scope,
+		Type:       claims.Type,
+	}
+	if claims.IssuedAt != nil {
+		p.CreationDate = claims.IssuedAt.UTC()
+	}
+	if claims.ExpiresAt != nil {
+		p.ExpiresIn = claims.ExpiresAt.Sub(p.CreationDate)
+	}
+	if claims.TokenID != "" {
+		p.ID = claims.TokenID
+		p.RefreshTokenID = claims.ID
+	}
+	if claims.UserID != 0 {
+		p.Claims = map[string]int{"user_id": claims.UserID}
+	}
+
+	return json.Marshal(p)
+}
+
+// EncodeToken returns JWT as is: it's already a string
+func (sc *JWTTokenSecureFormatter) EncodeToken(token []byte) string {
+	return string(token)
+}
+
+func (sc *JWTTokenSecureFormatter) DecodeToken(token string) ([]byte, error) {
+	return []byte(token), nil
+}
+
+func (sc *JWTTokenSecureFormatter) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := sc.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown kid")
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.VerifyKey, nil
+}
@@ -0,0 +1,189 @@
+package oauth
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "portal"
+
+func testToken(creationDate time.Time, ttl time.Duration) *Token {
+	return &Token{
+		ID:           "token-id",
+		SessionID:    "session-id",
+		CreationDate: creationDate,
+		ExpiresIn:    ttl,
+		Credential:   "ivanov",
+		Scope:        2,
+		Claims:       map[string]int{"user_id": 42},
+	}
+}
+
+func testRefreshToken(creationDate time.Time, ttl time.Duration) *RefreshToken {
+	return &RefreshToken{
+		CreationDate:   creationDate,
+		TokenID:        "token-id",
+		RefreshTokenID: "refresh-token-id",
+		SessionID:      "session-id",
+		Credential:     "ivanov",
+		Scope:          2,
+		ExpiresIn:      ttl,
+	}
+}
+
+func TestJWTRoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  JWTKey
+	}{
+		{name: "HS256", key: NewHS256Key("k1", []byte("secret"))},
+		{name: "EdDSA", key: JWTKey{ID: "k1", Method: jwt.SigningMethodEdDSA, SignKey: priv, VerifyKey: pub}},
+	}
+
+	// JWT хранит даты с точностью до секунды
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp := NewTokenProvider(NewJWTTokenSecurityProvider(testIssuer, tt.key))
+
+			access := testToken(now, time.Hour)
+			signed, err := tp.CryptToken(access)
+			if err != nil {
+				t.Fatalf("CryptToken() error = %v", err)
+			}
+			gotAccess, err := tp.DecryptToken(signed)
+			if err != nil {
+				t.Fatalf("DecryptToken() error = %v", err)
+			}
+			wantAccess := *access
+			wantAccess.Type = TokenTypeAccess
+			if !reflect.DeepEqual(*gotAccess, wantAccess) {
+				t.Errorf("DecryptToken() = %+v, want %+v", *gotAccess, wantAccess)
+			}
+
+			refresh := testRefreshToken(now, 24*time.Hour)
+			signedRefresh, err := tp.CryptRefreshToken(refresh)
+			if err != nil {
+				t.Fatalf("CryptRefreshToken() error = %v", err)
+			}
+			gotRefresh, err := tp.DecryptRefreshTokens(signedRefresh)
+			if err != nil {
+				t.Fatalf("DecryptRefreshTokens() error = %v", err)
+			}
+			wantRefresh := *refresh
+			wantRefresh.Type = TokenTypeRefresh
+			if !reflect.DeepEqual(*gotRefresh, wantRefresh) {
+				t.Errorf("DecryptRefreshTokens() = %+v, want %+v", *gotRefresh, wantRefresh)
+			}
+		})
+	}
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	oldKey := NewHS256Key("k1", []byte("old secret"))
+	newKey := NewHS256Key("k2", []byte("new secret"))
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	signedByOld, err := NewTokenProvider(NewJWTTokenSecurityProvider(testIssuer, oldKey)).CryptToken(testToken(time.Now().UTC(), time.Hour))
+	if err != nil {
+		t.Fatalf("CryptToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier *JWTTokenSecureFormatter
+		wantErr  bool
+	}{
+		{name: "old key kept as previous", verifier: NewJWTTokenSecurityProvider(testIssuer, newKey, oldKey)},
+		{name: "old key still active", verifier: NewJWTTokenSecurityProvider(testIssuer, oldKey, newKey)},
+		{name: "old key removed", verifier: NewJWTTokenSecurityProvider(testIssuer, newKey), wantErr: true},
+		{name: "same kid, other secret", verifier: NewJWTTokenSecurityProvider(testIssuer, NewHS256Key("k1", []byte("other"))), wantErr: true},
+		// Ключ с тем же kid, но другим алгоритмом не должен принимать токен
+		{name: "same kid, other algorithm", verifier: NewJWTTokenSecurityProvider(testIssuer, JWTKey{ID: "k1", Method: jwt.SigningMethodEdDSA, VerifyKey: pub}), wantErr: true},
+		{name: "other issuer", verifier: NewJWTTokenSecurityProvider("other", oldKey), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTokenProvider(tt.verifier).DecryptToken(signedByOld)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecryptToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Новые токены подписываются активным ключом
+	signedByNew, err := NewTokenProvider(NewJWTTokenSecurityProvider(testIssuer, newKey, oldKey)).CryptToken(testToken(time.Now().UTC(), time.Hour))
+	if err != nil {
+		t.Fatalf("CryptToken() error = %v", err)
+	}
+	token, _, err := jwt.NewParser().ParseUnverified(signedByNew, &jwtClaims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified() error = %v", err)
+	}
+	if kid := token.Header["kid"]; kid != newKey.ID {
+		t.Errorf("kid = %v, want %q", kid, newKey.ID)
+	}
+}
+
+func TestJWTInvalidTokens(t *testing.T) {
+	tp := NewTokenProvider(NewJWTTokenSecurityProvider(testIssuer, NewHS256Key("k1", []byte("secret"))))
+	now := time.Now().UTC()
+
+	access, err := tp.CryptToken(testToken(now, time.Hour))
+	if err != nil {
+		t.Fatalf("CryptToken() error = %v", err)
+	}
+	expiredAccess, err := tp.CryptToken(testToken(now.Add(-2*time.Hour), time.Hour))
+	if err != nil {
+		t.Fatalf("CryptToken() error = %v", err)
+	}
+	refresh, err := tp.CryptRefreshToken(testRefreshToken(now, time.Hour))
+	if err != nil {
+		t.Fatalf("CryptRefreshToken() error = %v", err)
+	}
+	expiredRefresh, err := tp.CryptRefreshToken(testRefreshToken(now.Add(-2*time.Hour), time.Hour))
+	if err != nil {
+		t.Fatalf("CryptRefreshToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		decrypt func() error
+		wantErr error
+	}{
+		{name: "expired access", decrypt: func() error { _, err := tp.DecryptToken(expiredAccess); return err }, wantErr: ErrTokenExpired},
+		{name: "expired refresh", decrypt: func() error { _, err := tp.DecryptRefreshTokens(expiredRefresh); return err }, wantErr: ErrTokenExpired},
+		{name: "refresh as access", decrypt: func() error { _, err := tp.DecryptToken(refresh); return err }, wantErr: ErrWrongTokenType},
+		{name: "access as refresh", decrypt: func() error { _, err := tp.DecryptRefreshTokens(access); return err }, wantErr: ErrWrongTokenType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.decrypt(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := tp.DecryptToken(access + "x"); err == nil {
+		t.Error("DecryptToken() with broken signature error = nil")
+	}
+	if _, err := tp.CryptToken(testToken(now, 0)); err == nil {
+		t.Error("CryptToken() without expiration error = nil")
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"log/slog"
 	"net/http"
 	"reflect"
-	"strconv"
+	"strings"
 	"time"
 
 	resp "portal/internal/lib/api/response"
@@ -19,11 +19,14 @@ import (
 
 type contextKey string
 
+var errInvalidAuthorizationHeader = errors.New("invalid authorization header")
+
 const (
 	CredentialContext  contextKey = "oauth.credential"
 	ClaimsContext      contextKey = "oauth.claims"
 	ScopeContext       contextKey = "oauth.scope"
 	AccessTokenContext contextKey = "oauth.accesstoken"
+	SessionContext     contextKey = "oauth.session"
 )
 
 // BearerAuthentication middleware for go-chi
@@ -38,7 +41,7 @@ type BearerAuthentication struct {
 func NewBearerAuthentication(secretKey string, formatter TokenSecureFormatter, bs *BearerServer, log *slog.Logger) *BearerAuthentication {
 	ba := &BearerAuthentication{secretKey: secretKey, BearerServer: bs, Log: log}
 	if formatter == nil {
-		formatter = NewSHA256RC4TokenSecurityProvider([]byte(secretKey))
+		formatter = NewJWTTokenSecurityProvider("", NewHS256Key("", []byte(secretKey)))
 	}
 	ba.provider = NewTokenProvider(formatter)
 	return ba
@@ -53,6 +56,7 @@ func Authorize(secretKey string, formatter TokenSecureFormatter, bs *BearerServe
 // Authorize verifies the bearer token authorizing or not the request.
 // Token is retrieved from the Authorization HTTP header that respects the format
 // Authorization: Bearer {access_token}
+// If there is no header, token is retrieved from the access_token cookie
 func (ba *BearerAuthentication) Authorize(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		const op = "lib.oauth.Authorize"
@@ -62,13 +66,18 @@ func (ba *BearerAuthentication) Authorize(next http.Handler) http.Handler {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		cookie, err := r.Cookie("access_token")
+		// Токен берется из заголовка Authorization, при его отсутствии из cookie
+		auth, fromHeader, err := accessTokenFromRequest(r)
 		if err != nil {
 			switch {
 			case errors.Is(err, http.ErrNoCookie):
 				log.Error("cookie not found")
 				w.WriteHeader(http.StatusUnauthorized)
 				render.JSON(w, r, resp.Error("cookie not found"))
+			case errors.Is(err, errInvalidAuthorizationHeader):
+				log.Error("invalid authorization header")
+				w.WriteHeader(http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("invalid authorization header"))
 			default:
 				log.Error("server error", sl.Err(err))
 				w.WriteHeader(http.StatusInternalServerError)
@@ -76,9 +85,8 @@ func (ba *BearerAuthentication) Authorize(next http.Handler) http.Handler {
 			}
 			return
 		}
-		auth := cookie.Value
 
-		token, err := ba.checkAuthorization(auth, w, r)
+		token, err := ba.checkAuthorization(auth, fromHeader, w, r)
 		if err != nil {
 			log.Error("Not authorized", sl.Err(err))
 			w.WriteHeader(http.StatusUnauthorized)
@@ -91,6 +99,7 @@ func (ba *BearerAuthentication) Authorize(next http.Handler) http.Handler {
 		ctx = context.WithValue(ctx, ClaimsContext, token.Claims)
 		ctx = context.WithValue(ctx, ScopeContext, token.Scope)
 		ctx = context.WithValue(ctx, AccessTokenContext, auth)
+		ctx = context.WithValue(ctx, SessionContext, token.SessionID)
 
 		log.Info("authorization success")
 
@@ -98,14 +107,88 @@ func (ba *BearerAuthentication) Authorize(next http.Handler) http.Handler {
 	})
 }
 
+// RequireUser rejects requests authorized by API client credentials with 403.
+// It is used for routes working with the current user: client tokens have no user claims
+func RequireUser(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "lib.oauth.RequireUser"
+
+			credential, _ := r.Context().Value(CredentialContext).(string)
+			if strings.HasPrefix(credential, ClientCredentialPrefix) {
+				log.Error("api client is not allowed",
+					slog.String("op", op),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.String("credential", credential),
+				)
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, resp.Error("api clients are not allowed"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// accessTokenFromRequest returns access token from the Authorization header or from the access_token cookie.
+// fromHeader reports the token was taken from the header
+func accessTokenFromRequest(r *http.Request) (auth string, fromHeader bool, err error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return "", false, errInvalidAuthorizationHeader
+		}
+		return strings.TrimSpace(token), true, nil
+	}
+
+	cookie, err := r.Cookie("access_token")
+	if err != nil {
+		return "", false, err
+	}
+
+	return cookie.Value, false, nil
+}
+
 // Check header and token.
-func (ba *BearerAuthentication) checkAuthorization(auth string, w http.ResponseWriter, r *http.Request) (t *Token, err error) {
+// Expired token from cookie is refreshed by refresh_token cookie, expired token from header is rejected:
+// such clients refresh tokens themselves through /api/login
+func (ba *BearerAuthentication) checkAuthorization(auth string, fromHeader bool, w http.ResponseWriter, r *http.Request) (t *Token, err error) {
 	token, err := ba.provider.DecryptToken(auth)
-	if err != nil {
+	expired := errors.Is(err, ErrTokenExpired)
+	if err != nil && !expired {
 		return nil, errors.New("Invalid token: " + err.Error())
 	}
 
-	if time.Now().UTC().After(token.CreationDate.Add(token.ExpiresIn)) {
+	if !expired {
+		// Токены отозванных сессий (выход, блокировка пользователя) недействительны до истечения срока
+		isRevoked, err := ba.BearerServer.verifier.IsTokenRevoked(r.Context(), token.ID)
+		if err != nil {
+			return nil, errors.New("Failed to check token revocation: " + err.Error())
+		}
+		if isRevoked {
+			return nil, errors.New("Token is revoked")
+		}
+
+		// Токены клиента перестают действовать сразу после его отключения или удаления
+		if clientID, ok := strings.CutPrefix(token.Credential, ClientCredentialPrefix); ok {
+			isActive, err := ba.BearerServer.verifier.IsClientActive(r.Context(), clientID)
+			if err != nil {
+				return nil, errors.New("Failed to check api client: " + err.Error())
+			}
+			if !isActive {
+				return nil, errors.New("Api client is inactive")
+			}
+		}
+
+		expired = time.Now().UTC().After(token.CreationDate.Add(token.ExpiresIn))
+	}
+
+	if expired {
+		if fromHeader {
+			return nil, errors.New("Token is expired")
+		}
+
 		cookie, err := r.Cookie("refresh_token")
 		if err != nil {
 			switch {
@@ -117,35 +200,20 @@ func (ba *BearerAuthentication) checkAuthorization(auth string, w http.ResponseW
 			return nil, fmt.Errorf("Not authorized: " + err.Error())
 		}
 		refreshToken := cookie.Value
-		response, role, statusCode := ba.BearerServer.generateTokenResponse(GrantType("refresh_token"), "", "", refreshToken, r)
+		response, role, statusCode := ba.BearerServer.generateTokenResponse(GrantType("refresh_token"), "", "", refreshToken, "", r)
 
 		if statusCode != 200 {
 			return nil, errors.New("Error while token generating: " + reflect.ValueOf(response).String())
 		}
 
-		http.SetCookie(w,
-			&http.Cookie{
-				Name:     "access_token",
-				Value:    reflect.Indirect(reflect.ValueOf(response)).FieldByName("Token").String(),
-				Expires:  time.Now().Add(2160 * time.Hour), // Время зачитски куки из браузера (ttl находитися в token.ExpiresIn)
-				HttpOnly: true,
-			})
-
-		http.SetCookie(w,
-			&http.Cookie{
-				Name:     "refresh_token",
-				Value:    reflect.Indirect(reflect.ValueOf(response)).FieldByName("RefreshToken").String(),
-				Expires:  time.Now().Add(2160 * time.Hour),
-				HttpOnly: true,
-			})
-
-		http.SetCookie(w,
-			&http.Cookie{
-				Name:     "role",
-				Value:    strconv.Itoa(role),
-				Expires:  time.Now().Add(2160 * time.Hour),
-				HttpOnly: true,
-			})
+		if err := ba.BearerServer.setAuthCookies(w, response.(*TokenResponse), role); err != nil {
+			return nil, errors.New("Failed to generate csrf token: " + err.Error())
+		}
+
+		// Запрос выполняется с данными нового токена
+		if token, err = ba.provider.DecryptToken(response.(*TokenResponse).Token); err != nil {
+			return nil, errors.New("Invalid token: " + err.Error())
+		}
 	}
 	return token, nil
 
@@ -6,11 +6,37 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"time"
+)
+
+// Token types written to typ field: access token can't be used as refresh token and vice versa
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrTokenExpired   = errors.New("token is expired")
+	ErrWrongTokenType = errors.New("wrong token type")
 )
 
 type TokenSecureFormatter interface {
 	CryptToken(source []byte) ([]byte, error)
 	DecryptToken(source []byte) ([]byte, error)
+	// EncodeToken converts crypted token to the string passed to the client, DecodeToken does the reverse
+	EncodeToken(token []byte) string
+	DecodeToken(token string) ([]byte, error)
+}
+
+// base64Encoding encodes binary tokens of RC4 formatters
+type base64Encoding struct{}
+
+func (base64Encoding) EncodeToken(token []byte) string {
+	return base64.StdEncoding.EncodeToString(token)
+}
+
+func (base64Encoding) DecodeToken(token string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(token)
 }
 
 type TokenProvider struct {
@@ -22,7 +48,9 @@ func NewTokenProvider(formatter TokenSecureFormatter) *TokenProvider {
 }
 
 func (tp *TokenProvider) CryptToken(t *Token) (token string, err error) {
-	bToken, err := json.Marshal(t)
+	typed := *t
+	typed.Type = TokenTypeAccess
+	bToken, err := json.Marshal(typed)
 	if err != nil {
 		return "", err
 	}
@@ -30,7 +58,9 @@ func (tp *TokenProvider) CryptToken(t *Token) (token string, err error) {
 }
 
 func (tp *TokenProvider) CryptRefreshToken(t *RefreshToken) (token string, err error) {
-	bToken, err := json.Marshal(t)
+	typed := *t
+	typed.Type = TokenTypeRefresh
+	bToken, err := json.Marshal(typed)
 	if err != nil {
 		return "", err
 	}
@@ -46,6 +76,9 @@ func (tp *TokenProvider) DecryptToken(token string) (t *Token, err error) {
 	if err != nil {
 		return nil, err
 	}
+	if t.Type != TokenTypeAccess {
+		return nil, ErrWrongTokenType
+	}
 	return t, nil
 }
 
@@ -58,6 +91,13 @@ func (tp *TokenProvider) DecryptRefreshTokens(refreshToken string) (refresh *Ref
 	if err != nil {
 		return nil, err
 	}
+	if refresh.Type != TokenTypeRefresh {
+		return nil, ErrWrongTokenType
+	}
+	// Срок действия проверяется и здесь: не все форматы токенов проверяют его сами
+	if refresh.ExpiresIn == 0 || time.Now().UTC().After(refresh.CreationDate.Add(refresh.ExpiresIn)) {
+		return nil, ErrTokenExpired
+	}
 	return refresh, nil
 }
 
@@ -66,11 +106,11 @@ func (tp *TokenProvider) crypt(token []byte) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return base64.StdEncoding.EncodeToString(ctoken), nil
+	return tp.secureFormatter.EncodeToken(ctoken), nil
 }
 
 func (tp *TokenProvider) decrypt(token string) ([]byte, error) {
-	b, err := base64.StdEncoding.DecodeString(token)
+	b, err := tp.secureFormatter.DecodeToken(token)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +118,7 @@ func (tp *TokenProvider) decrypt(token string) ([]byte, error) {
 }
 
 type RC4TokenSecureFormatter struct {
+	base64Encoding
 	key    []byte
 	cipher *rc4.Cipher
 }
@@ -108,6 +149,7 @@ func (sc *RC4TokenSecureFormatter) DecryptToken(source []byte) ([]byte, error) {
 }
 
 type SHA256RC4TokenSecureFormatter struct {
+	base64Encoding
 	key    []byte
 	cipher *rc4.Cipher
 }
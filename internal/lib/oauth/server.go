@@ -1,11 +1,10 @@
 package oauth
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
-	"reflect"
-	"strconv"
 	"time"
 
 	resp "portal/internal/lib/api/response"
@@ -27,41 +26,80 @@ const (
 type CredentialsVerifier interface {
 	// ValidateUser validates username and password returning an scope value and an error if the user credentials are wrong
 	ValidateUser(username, password string, r *http.Request) (int, error)
+	// ValidateClient validates client credentials returning a scope value from the client allowed scopes
+	ValidateClient(ctx context.Context, clientID, clientSecret, scope string) (int, error)
 	// Provide additional claims to the token
 	AddClaims(credential, tokenID string, scope int, r *http.Request) (map[string]int, error)
-	// Optionally validate previously stored tokenID during refresh request
-	ValidateTokenID(credential, tokenID, refreshTokenID string) error
-	// Optionally store the tokenID generated for the user
-	StoreTokenID(credential, tokenID, refreshTokenID string) error
+	// Optionally rotate token IDs of the session during refresh request returning the access token ID of the session.
+	// The previous refresh token ID is accepted during grace period returning the current access token ID of the session,
+	// any other not latest refresh token ID of the session means reuse and revokes the session
+	RotateTokenID(ctx context.Context, credential, sessionID, oldRefreshTokenID, tokenID, refreshTokenID string, grace time.Duration) (string, error)
+	// Optionally store the session of the user device with the token IDs
+	StoreTokenID(credential, sessionID, deviceID, tokenID, refreshTokenID string, r *http.Request) error
+	// Revoke the session and its access token
+	RevokeSession(ctx context.Context, credential, sessionID string) error
+	// Check the access token ID is in denylist
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// Check the API client exists and is active. Tokens of deactivated or deleted clients are rejected
+	IsClientActive(ctx context.Context, clientID string) (bool, error)
 }
 
+const (
+	// DefaultRefreshTokenTTL is the refresh token lifetime, each refresh issues a new refresh token
+	DefaultRefreshTokenTTL = 720 * time.Hour
+	// DefaultRefreshGracePeriod is the time the previous refresh token of the session is accepted after rotation
+	DefaultRefreshGracePeriod = 30 * time.Second
+)
+
 // BearerServer is the OAuth 2 bearer server implementation.
 type BearerServer struct {
 	secretKey string
 	TokenTTL  time.Duration
 	verifier  CredentialsVerifier
 	provider  *TokenProvider
+	Cookie    CookieOptions
+
+	RefreshTokenTTL time.Duration
+	// Параллельные запросы с одним истекшим токеном обновляют его одним и тем же refresh токеном
+	RefreshGracePeriod time.Duration
 }
 
 // NewBearerServer creates new OAuth 2 bearer server
 func NewBearerServer(secretKey string, ttl time.Duration, verifier CredentialsVerifier, formatter TokenSecureFormatter) *BearerServer {
 	if formatter == nil {
-		formatter = NewSHA256RC4TokenSecurityProvider([]byte(secretKey))
+		formatter = NewJWTTokenSecurityProvider("", NewHS256Key("", []byte(secretKey)))
 	}
 	return &BearerServer{
 		secretKey: secretKey,
 		TokenTTL:  ttl,
 		verifier:  verifier,
-		provider:  NewTokenProvider(formatter)}
+		provider:  NewTokenProvider(formatter),
+		Cookie:    DefaultCookieOptions(),
+
+		RefreshTokenTTL:    DefaultRefreshTokenTTL,
+		RefreshGracePeriod: DefaultRefreshGracePeriod}
 }
 
-// UserCredentials manages password grant type requests
-func (bs *BearerServer) UserCredentials(w http.ResponseWriter, r *http.Request) {
-	grantType := "password"
+const responseModeJSON = "json"
+
+// LoginResponse is the token response for non-browser clients. DeviceID should be sent with the next
+// password grant requests of the device to replace its session instead of opening a new one
+type LoginResponse struct {
+	resp.Response
+	TokenResponse
+	DeviceID string `json:"device_id,omitempty"`
+}
 
+// UserCredentials manages password and refresh_token grant type requests.
+// By default tokens are set to cookies, with response_mode "json" they are returned in the response body
+func (bs *BearerServer) UserCredentials(w http.ResponseWriter, r *http.Request) {
 	type UserData struct {
-		Username string `json:"username" validate:"required"`
-		Password string `json:"password" validate:"required"`
+		Username     string `json:"username"`
+		Password     string `json:"password"`
+		GrantType    string `json:"grant_type"`
+		RefreshToken string `json:"refresh_token"`
+		ResponseMode string `json:"response_mode"`
+		DeviceID     string `json:"device_id"`
 	}
 	var userData UserData
 	// Декодируем json запроса
@@ -79,11 +117,34 @@ func (bs *BearerServer) UserCredentials(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	refreshToken := ""
-	response, role, statusCode := bs.generateTokenResponse(GrantType(grantType), userData.Username, userData.Password, refreshToken, r)
+	grantType := PasswordGrant
+	var deviceID string
+	switch GrantType(userData.GrantType) {
+	case "", PasswordGrant:
+		if userData.Username == "" || userData.Password == "" {
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("field Username and Password are required fields"))
+			return
+		}
+		deviceID = requestDeviceID(userData.DeviceID, r)
+	case RefreshTokenGrant:
+		// Обновление токенов в теле запроса доступно только клиентам без cookie
+		if userData.RefreshToken == "" || userData.ResponseMode != responseModeJSON {
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("refresh_token grant requires refresh_token and json response_mode"))
+			return
+		}
+		grantType = RefreshTokenGrant
+	default:
+		w.WriteHeader(400)
+		render.JSON(w, r, resp.Error("Invalid grant_type"))
+		return
+	}
+
+	response, role, statusCode := bs.generateTokenResponse(grantType, userData.Username, userData.Password, userData.RefreshToken, deviceID, r)
 
 	if statusCode != 200 {
-		if statusCode == 401 {
+		if statusCode == 401 && grantType == PasswordGrant {
 			w.WriteHeader(401)
 			render.JSON(w, r, resp.Alert("Имя пользователя или пароль указан неверно. Попробуйте ещё раз."))
 			return
@@ -93,50 +154,71 @@ func (bs *BearerServer) UserCredentials(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	http.SetCookie(w,
-		&http.Cookie{
-			Name:     "access_token",
-			Value:    reflect.Indirect(reflect.ValueOf(response)).FieldByName("Token").String(),
-			Expires:  time.Now().Add(2160 * time.Hour), // Время зачитски куки из браузера (ttl находитися в bs.TokenTTL)
-			HttpOnly: true,
-		})
-
-	http.SetCookie(w,
-		&http.Cookie{
-			Name:     "refresh_token",
-			Value:    reflect.Indirect(reflect.ValueOf(response)).FieldByName("RefreshToken").String(),
-			Expires:  time.Now().Add(2160 * time.Hour),
-			HttpOnly: true,
-		})
-
-	http.SetCookie(w,
-		&http.Cookie{
-			Name:     "role",
-			Value:    strconv.Itoa(role),
-			Expires:  time.Now().Add(2160 * time.Hour),
-			HttpOnly: true,
-		})
+	if userData.ResponseMode == responseModeJSON {
+		render.JSON(w, r, LoginResponse{Response: resp.OK(), TokenResponse: *response.(*TokenResponse), DeviceID: deviceID})
+		return
+	}
+
+	if err := bs.setAuthCookies(w, response.(*TokenResponse), role); err != nil {
+		w.WriteHeader(500)
+		render.JSON(w, r, resp.Error("failed to generate csrf token: "+err.Error()))
+		return
+	}
+	if deviceID != "" {
+		bs.setDeviceCookie(w, deviceID)
+	}
 
 	render.JSON(w, r, resp.OK())
 }
 
-// Generate token response
-func (bs *BearerServer) generateTokenResponse(grantType GrantType, credential string, secret string, refreshToken string, r *http.Request) (interface{}, int, int) {
+// Logout revokes the session of the request and clears auth cookies
+func (bs *BearerServer) Logout(w http.ResponseWriter, r *http.Request) {
+	// Сессию определяем по refresh токену, т.к. access токен мог уже истечь.
+	// Клиенты без cookie передают access токен в заголовке Authorization
+	var credential, sessionID string
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		if refresh, err := bs.provider.DecryptRefreshTokens(cookie.Value); err == nil {
+			credential, sessionID = refresh.Credential, refresh.SessionID
+		}
+	} else if auth, fromHeader, err := accessTokenFromRequest(r); err == nil && fromHeader {
+		if token, err := bs.provider.DecryptToken(auth); err == nil {
+			credential, sessionID = token.Credential, token.SessionID
+		}
+	}
+
+	if sessionID != "" {
+		if err := bs.verifier.RevokeSession(r.Context(), credential, sessionID); err != nil {
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("failed to revoke session: "+err.Error()))
+			return
+		}
+	}
+
+	bs.clearAuthCookies(w)
+
+	render.JSON(w, r, resp.OK())
+}
+
+// Generate token response. For client_credentials grant refreshToken param contains requested scope,
+// deviceID is used only by password grant
+func (bs *BearerServer) generateTokenResponse(grantType GrantType, credential string, secret string, refreshToken string, deviceID string, r *http.Request) (interface{}, int, int) {
 	var response *TokenResponse
 	var scope int
+	var err error
 	switch grantType {
 	case PasswordGrant:
-		scope, err := bs.verifier.ValidateUser(credential, secret, r)
+		scope, err = bs.verifier.ValidateUser(credential, secret, r)
 		if err != nil {
 			return "Not authorized: " + err.Error(), 0, http.StatusUnauthorized
 		}
 
-		token, refresh, err := bs.generateTokens(credential, scope, r)
+		// Каждый вход открывает новую сессию (семейство refresh токенов) для устройства
+		token, refresh, err := bs.generateTokens(credential, scope, uuid.Must(uuid.NewV4()).String(), r)
 		if err != nil {
 			return "Token generation failed, check claims: " + err.Error(), 0, http.StatusInternalServerError
 		}
 
-		if err = bs.verifier.StoreTokenID(credential, token.ID, refresh.RefreshTokenID); err != nil {
+		if err = bs.verifier.StoreTokenID(credential, refresh.SessionID, deviceID, token.ID, refresh.RefreshTokenID, r); err != nil {
 			return "Storing Token ID failed: " + err.Error(), 0, http.StatusInternalServerError
 		}
 
@@ -144,28 +226,45 @@ func (bs *BearerServer) generateTokenResponse(grantType GrantType, credential st
 			return "Token generation failed, check security provider: " + err.Error(), 0, http.StatusInternalServerError
 		}
 	case RefreshTokenGrant:
-		refresh, err := bs.provider.DecryptRefreshTokens(refreshToken)
+		oldRefresh, err := bs.provider.DecryptRefreshTokens(refreshToken)
 		if err != nil {
 			return "Not authorized: " + err.Error(), 0, http.StatusUnauthorized
 		}
+		scope = oldRefresh.Scope
 
-		if err = bs.verifier.ValidateTokenID(refresh.Credential, refresh.TokenID, refresh.RefreshTokenID); err != nil {
-			return "Not authorized invalid token: " + err.Error(), 0, http.StatusUnauthorized
-		}
-
-		token, refresh, err := bs.generateTokens(refresh.Credential, refresh.Scope, r)
+		// При каждом обновлении выдается новый refresh токен в рамках той же сессии
+		token, refresh, err := bs.generateTokens(oldRefresh.Credential, scope, oldRefresh.SessionID, r)
 		if err != nil {
 			return "Token generation failed: " + err.Error(), 0, http.StatusInternalServerError
 		}
 
-		err = bs.verifier.StoreTokenID(refresh.Credential, token.ID, refresh.RefreshTokenID)
+		tokenID, err := bs.verifier.RotateTokenID(r.Context(), oldRefresh.Credential, oldRefresh.SessionID, oldRefresh.RefreshTokenID, token.ID, refresh.RefreshTokenID, bs.RefreshGracePeriod)
 		if err != nil {
-			return "Storing Token ID failed: " + err.Error(), 0, http.StatusInternalServerError
+			return "Not authorized invalid token: " + err.Error(), 0, http.StatusUnauthorized
+		}
+		// Токен уже обновлен параллельным запросом: выдается только access токен текущей сессии,
+		// новый refresh токен, выданный параллельным запросом, остается действующим
+		if tokenID != token.ID {
+			token.ID, refresh = tokenID, nil
 		}
 
 		if response, err = bs.cryptTokens(token, refresh); err != nil {
 			return "Token generation failed: " + err.Error(), 0, http.StatusInternalServerError
 		}
+	case ClientCredentialsGrant:
+		scope, err = bs.verifier.ValidateClient(r.Context(), credential, secret, refreshToken)
+		if err != nil {
+			return "Not authorized: " + err.Error(), 0, http.StatusUnauthorized
+		}
+
+		// Сервисный клиент получает только access токен, без сессии и refresh токена
+		token := &Token{ID: uuid.Must(uuid.NewV4()).String(), Credential: ClientCredentialPrefix + credential, ExpiresIn: bs.TokenTTL, CreationDate: time.Now().UTC(), Scope: scope, Claims: map[string]int{}}
+		cToken, err := bs.provider.CryptToken(token)
+		if err != nil {
+			return "Token generation failed, check security provider: " + err.Error(), 0, http.StatusInternalServerError
+		}
+
+		response = &TokenResponse{Token: cToken, TokenType: "Bearer", ExpiresIn: (int64)(bs.TokenTTL / time.Second)}
 	default:
 		return "Invalid grant_type", 0, http.StatusBadRequest
 	}
@@ -173,8 +272,8 @@ func (bs *BearerServer) generateTokenResponse(grantType GrantType, credential st
 	return response, scope, http.StatusOK
 }
 
-func (bs *BearerServer) generateTokens(username string, scope int, r *http.Request) (*Token, *RefreshToken, error) {
-	token := &Token{ID: uuid.Must(uuid.NewV4()).String(), Credential: username, ExpiresIn: bs.TokenTTL, CreationDate: time.Now().UTC(), Scope: scope}
+func (bs *BearerServer) generateTokens(username string, scope int, sessionID string, r *http.Request) (*Token, *RefreshToken, error) {
+	token := &Token{ID: uuid.Must(uuid.NewV4()).String(), SessionID: sessionID, Credential: username, ExpiresIn: bs.TokenTTL, CreationDate: time.Now().UTC(), Scope: scope}
 	if bs.verifier != nil {
 		claims, err := bs.verifier.AddClaims(username, token.ID, token.Scope, r)
 		if err != nil {
@@ -183,7 +282,7 @@ func (bs *BearerServer) generateTokens(username string, scope int, r *http.Reque
 		token.Claims = claims
 	}
 
-	refreshToken := &RefreshToken{RefreshTokenID: uuid.Must(uuid.NewV4()).String(), TokenID: token.ID, CreationDate: time.Now().UTC(), Credential: username, Scope: scope}
+	refreshToken := &RefreshToken{RefreshTokenID: uuid.Must(uuid.NewV4()).String(), TokenID: token.ID, SessionID: sessionID, CreationDate: time.Now().UTC(), ExpiresIn: bs.RefreshTokenTTL, Credential: username, Scope: scope}
 
 	return token, refreshToken, nil
 }
@@ -193,12 +292,14 @@ func (bs *BearerServer) cryptTokens(token *Token, refresh *RefreshToken) (*Token
 	if err != nil {
 		return nil, err
 	}
-	cRefreshToken, err := bs.provider.CryptRefreshToken(refresh)
-	if err != nil {
-		return nil, err
+	tokenResponse := &TokenResponse{Token: cToken, TokenType: "Bearer", ExpiresIn: (int64)(bs.TokenTTL / time.Second)}
+	if refresh == nil {
+		return tokenResponse, nil
 	}
 
-	tokenResponse := &TokenResponse{Token: cToken, RefreshToken: cRefreshToken, ExpiresIn: (int64)(bs.TokenTTL / time.Second)}
+	if tokenResponse.RefreshToken, err = bs.provider.CryptRefreshToken(refresh); err != nil {
+		return nil, err
+	}
 
 	return tokenResponse, nil
 }
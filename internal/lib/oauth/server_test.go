@@ -0,0 +1,221 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSession struct {
+	tokenID            string
+	refreshTokenID     string
+	prevRefreshTokenID string
+	rotationDate       time.Time
+}
+
+// fakeVerifier keeps sessions in memory with the same rotation rules as user.Session.RotateRefreshTokenID
+type fakeVerifier struct {
+	mu       sync.Mutex
+	sessions map[string]*fakeSession
+}
+
+func newFakeVerifier() *fakeVerifier {
+	return &fakeVerifier{sessions: map[string]*fakeSession{}}
+}
+
+func (v *fakeVerifier) ValidateUser(username, password string, r *http.Request) (int, error) {
+	if password != "password" {
+		return 0, errors.New("wrong password")
+	}
+	return 1, nil
+}
+
+func (v *fakeVerifier) ValidateClient(ctx context.Context, clientID, clientSecret, scope string) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (v *fakeVerifier) AddClaims(credential, tokenID string, scope int, r *http.Request) (map[string]int, error) {
+	return map[string]int{"user_id": 42}, nil
+}
+
+func (v *fakeVerifier) RotateTokenID(ctx context.Context, credential, sessionID, oldRefreshTokenID, tokenID, refreshTokenID string, grace time.Duration) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s, ok := v.sessions[sessionID]
+	if !ok {
+		return "", errors.New("session does not exist")
+	}
+	switch {
+	case s.refreshTokenID == oldRefreshTokenID:
+		s.prevRefreshTokenID, s.refreshTokenID, s.tokenID, s.rotationDate = oldRefreshTokenID, refreshTokenID, tokenID, time.Now()
+		return tokenID, nil
+	case s.prevRefreshTokenID == oldRefreshTokenID && time.Since(s.rotationDate) <= grace:
+		return s.tokenID, nil
+	default:
+		delete(v.sessions, sessionID)
+		return "", errors.New("refresh token reused")
+	}
+}
+
+func (v *fakeVerifier) StoreTokenID(credential, sessionID, deviceID, tokenID, refreshTokenID string, r *http.Request) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.sessions[sessionID] = &fakeSession{tokenID: tokenID, refreshTokenID: refreshTokenID}
+	return nil
+}
+
+func (v *fakeVerifier) RevokeSession(ctx context.Context, credential, sessionID string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.sessions, sessionID)
+	return nil
+}
+
+func (v *fakeVerifier) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return false, nil
+}
+
+func (v *fakeVerifier) IsClientActive(ctx context.Context, clientID string) (bool, error) {
+	return false, nil
+}
+
+func (v *fakeVerifier) session(sessionID string) (fakeSession, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s, ok := v.sessions[sessionID]
+	if !ok {
+		return fakeSession{}, false
+	}
+	return *s, true
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	verifier := newFakeVerifier()
+	bs := NewBearerServer("secret", time.Minute, verifier, nil)
+	r := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+
+	login := func(t *testing.T) *TokenResponse {
+		t.Helper()
+		resp, _, status := bs.generateTokenResponse(PasswordGrant, "ivanov", "password", "", "", r)
+		if status != http.StatusOK {
+			t.Fatalf("password grant status = %d, response = %v", status, resp)
+		}
+		return resp.(*TokenResponse)
+	}
+	refresh := func(refreshToken string) (*TokenResponse, int) {
+		resp, _, status := bs.generateTokenResponse(RefreshTokenGrant, "", "", refreshToken, "", r)
+		tr, _ := resp.(*TokenResponse)
+		return tr, status
+	}
+	sessionID := func(t *testing.T, refreshToken string) string {
+		t.Helper()
+		rt, err := bs.provider.DecryptRefreshTokens(refreshToken)
+		if err != nil {
+			t.Fatalf("DecryptRefreshTokens() error = %v", err)
+		}
+		return rt.SessionID
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		first := login(t)
+		second, status := refresh(first.RefreshToken)
+		if status != http.StatusOK {
+			t.Fatalf("refresh status = %d, want %d", status, http.StatusOK)
+		}
+		if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+			t.Fatal("refresh didn't issue new refresh token")
+		}
+		if sessionID(t, second.RefreshToken) != sessionID(t, first.RefreshToken) {
+			t.Error("refresh changed session ID")
+		}
+
+		// Новый refresh токен продолжает цепочку
+		if _, status := refresh(second.RefreshToken); status != http.StatusOK {
+			t.Errorf("refresh by new token status = %d, want %d", status, http.StatusOK)
+		}
+	})
+
+	t.Run("parallel refresh in grace period", func(t *testing.T) {
+		bs.RefreshGracePeriod = time.Minute
+
+		first := login(t)
+		second, status := refresh(first.RefreshToken)
+		if status != http.StatusOK {
+			t.Fatalf("refresh status = %d, want %d", status, http.StatusOK)
+		}
+
+		parallel, status := refresh(first.RefreshToken)
+		if status != http.StatusOK {
+			t.Fatalf("parallel refresh status = %d, want %d", status, http.StatusOK)
+		}
+		if parallel.RefreshToken != "" {
+			t.Error("parallel refresh issued refresh token")
+		}
+		access, err := bs.provider.DecryptToken(parallel.Token)
+		if err != nil {
+			t.Fatalf("DecryptToken() error = %v", err)
+		}
+		session, ok := verifier.session(sessionID(t, first.RefreshToken))
+		if !ok {
+			t.Fatal("session revoked by parallel refresh")
+		}
+		if access.ID != session.tokenID {
+			t.Errorf("access token ID = %q, want current session token ID %q", access.ID, session.tokenID)
+		}
+
+		// Refresh токен, выданный первым запросом, остается действующим
+		if _, status := refresh(second.RefreshToken); status != http.StatusOK {
+			t.Errorf("refresh by rotated token status = %d, want %d", status, http.StatusOK)
+		}
+	})
+
+	t.Run("reuse revokes session", func(t *testing.T) {
+		bs.RefreshGracePeriod = 0
+
+		first := login(t)
+		second, status := refresh(first.RefreshToken)
+		if status != http.StatusOK {
+			t.Fatalf("refresh status = %d, want %d", status, http.StatusOK)
+		}
+
+		if _, status := refresh(first.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("reused refresh status = %d, want %d", status, http.StatusUnauthorized)
+		}
+		if _, ok := verifier.session(sessionID(t, first.RefreshToken)); ok {
+			t.Error("session is not revoked after reuse")
+		}
+		// После отзыва сессии не работает и последний выданный refresh токен
+		if _, status := refresh(second.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("refresh after revoke status = %d, want %d", status, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("invalid refresh tokens", func(t *testing.T) {
+		first := login(t)
+
+		tests := []struct {
+			name         string
+			refreshToken string
+		}{
+			{name: "empty", refreshToken: ""},
+			{name: "garbage", refreshToken: "not a token"},
+			{name: "access token", refreshToken: first.Token},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, status := refresh(tt.refreshToken); status != http.StatusUnauthorized {
+					t.Errorf("refresh status = %d, want %d", status, http.StatusUnauthorized)
+				}
+			})
+		}
+	})
+}
@@ -1,15 +1,23 @@
 package oauth
 
 import (
+	"context"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"portal/internal/lib/logger/sl"
 	storageHandler "portal/internal/storage"
 	ldapServer "portal/internal/storage/ldap"
 	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/client"
+	"portal/internal/storage/postgres/entities/role"
 	"portal/internal/storage/postgres/entities/user"
+	"portal/internal/structs/permissions"
 	"portal/internal/structs/roles"
+	"slices"
+	"strconv"
+	"time"
 )
 
 // UserVerifier provides user credentials verifier for testing. Все методы этой структуры нужны для удовлетворения условиям NewBearerServer
@@ -18,6 +26,92 @@ type UserVerifier struct {
 	//Storage1C  *mssql.Storage
 	LDAPServer *ldapServer.LDAPServer
 	Log        *slog.Logger
+	TokenTTL   time.Duration // Записи denylist старше TokenTTL удаляются, т.к. токены в них уже истекли
+	// Соответствие групп LDAP ролям портала. Пустое значение - DefaultLDAPRoleMapping
+	RoleMapping LDAPRoleMapping
+}
+
+// LDAPGroupRole maps members of LDAP group to portal role
+type LDAPGroupRole struct {
+	Group string
+	Role  int
+}
+
+// LDAPGroupDeny denies permissions to members of LDAP group regardless of their roles
+type LDAPGroupDeny struct {
+	Group       string
+	Permissions []string
+}
+
+// LDAPRoleMapping defines main user role by LDAP groups. Groups are checked in order, the first match wins,
+// DefaultRole is used if user is not a member of any group. Permissions of all matched Deny groups are
+// denied to the user even if an additional role grants them
+type LDAPRoleMapping struct {
+	Groups      []LDAPGroupRole
+	DefaultRole int
+	Deny        []LDAPGroupDeny
+}
+
+// DefaultLDAPRoleMapping allows reservation only for ITR group members which are not in ReservationDeny group
+func DefaultLDAPRoleMapping() LDAPRoleMapping {
+	return LDAPRoleMapping{
+		Groups: []LDAPGroupRole{
+			{Group: "ReservationDeny", Role: roles.UserWithOutReservation},
+			{Group: "KD Heads employees and specialists", Role: roles.User},
+		},
+		DefaultRole: roles.UserWithOutReservation,
+		Deny: []LDAPGroupDeny{
+			{Group: "ReservationDeny", Permissions: []string{permissions.ReservationCreate}},
+		},
+	}
+}
+
+// manages reports the role is assigned by LDAP mapping. Other roles (e.g. SuperAdmin) are set by admin and kept on login
+func (m LDAPRoleMapping) manages(roleID int) bool {
+	if roleID == m.DefaultRole {
+		return true
+	}
+	return slices.ContainsFunc(m.Groups, func(g LDAPGroupRole) bool { return g.Role == roleID })
+}
+
+func (uv *UserVerifier) roleMapping() LDAPRoleMapping {
+	if len(uv.RoleMapping.Groups) == 0 && uv.RoleMapping.DefaultRole == 0 && len(uv.RoleMapping.Deny) == 0 {
+		return DefaultLDAPRoleMapping()
+	}
+	return uv.RoleMapping
+}
+
+// ldapRole returns the role of the user by LDAP groups membership
+func (uv *UserVerifier) ldapRole(username string) (int, error) {
+	mapping := uv.roleMapping()
+	for _, g := range mapping.Groups {
+		isMember, err := uv.LDAPServer.IsUserMemberOf(username, g.Group)
+		if err != nil {
+			return 0, err
+		}
+		if isMember {
+			return g.Role, nil
+		}
+	}
+
+	return mapping.DefaultRole, nil
+}
+
+// ldapDeniedPermissions returns permissions denied to the user by LDAP groups membership
+func (uv *UserVerifier) ldapDeniedPermissions(username string) ([]string, error) {
+	denied := []string{}
+	for _, g := range uv.roleMapping().Deny {
+		isMember, err := uv.LDAPServer.IsUserMemberOf(username, g.Group)
+		if err != nil {
+			return nil, err
+		}
+		if isMember {
+			denied = append(denied, g.Permissions...)
+		}
+	}
+
+	slices.Sort(denied)
+	return slices.Compact(denied), nil
 }
 
 // ValidateUser validates username and password returning an error if the user credentials are wrong
@@ -31,7 +125,7 @@ func (uv *UserVerifier) ValidateUser(username, password string, r *http.Request)
 		log.Error(op, "failed to get user info", sl.Err(err))
 		return 0, errors.New("failed to get user info: " + err.Error())
 	}
-	err = uv.LDAPServer.LDAPConn.Bind(userInfo[0], password)
+	err = uv.LDAPServer.Authenticate(userInfo.DN, password)
 	if err != nil {
 		log.Error(op, "LDAP user validation error", sl.Err(err))
 		return 0, errors.New("LDAP user validation error: " + err.Error())
@@ -45,37 +139,39 @@ func (uv *UserVerifier) ValidateUser(username, password string, r *http.Request)
 		return 0, errors.New("user validation error: " + err.Error())
 	}*/
 
+	// Роль по группам LDAP проверяется при каждом входе
+	ldapRole, err := uv.ldapRole(username)
+	if err != nil {
+		log.Error(op, "failed to check is user member of group", sl.Err(err))
+		return 0, errors.New("token claims error: " + err.Error())
+	}
+
 	// Get user id
 	var u user.User
-	u.Role = roles.User
-	err = u.GetUserID(uv.Storage, username)
+	err = u.GetUserID(r.Context(), uv.Storage.DB, username)
 	if err != nil {
 		// Если ошибка не об отсутствии user_id, то выход по стнадартной ошибке БД
-		if !errors.As(err, &storageHandler.ErrUserIDDoesNotExist) {
+		if !errors.Is(err, storageHandler.ErrUserIDDoesNotExist) {
 			log.Error(op, "failed to get user id", sl.Err(err))
 			return 0, errors.New("token claims error: " + err.Error())
 		}
-		isMemberOfGroupITR, err := uv.LDAPServer.IsUserMemberOf(username, "KD Heads employees and specialists")
-		if err != nil {
-			log.Error(op, "failed to check is user member of group", sl.Err(err))
-			return 0, errors.New("token claims error: " + err.Error())
-		}
-		isMemberOfGroupRsrvDeny, err := uv.LDAPServer.IsUserMemberOf(username, "ReservationDeny")
-		if err != nil {
-			log.Error(op, "failed to check is user member of group", sl.Err(err))
-			return 0, errors.New("token claims error: " + err.Error())
-		}
-		if !isMemberOfGroupITR || isMemberOfGroupRsrvDeny {
-			u.Role = roles.UserWithOutReservation
-		}
 		// Если ошибка выше была об отсутствии user_id, то создаем user_id для пользователя и получаем его в u.UserID
-		if err := u.NewUser(uv.Storage, username, userInfo[1], userInfo[2], userInfo[3], userInfo[4], userInfo[5], u.Role); err != nil {
+		if err := u.NewUser(r.Context(), uv.Storage.DB, username, userInfo.Name, userInfo.Title, userInfo.Department, userInfo.Mobile, userInfo.Mail, ldapRole); err != nil {
 			log.Error(op, "failed to create user in postgres", sl.Err(err))
 			return 0, errors.New("token claims error: " + err.Error())
 		}
+	} else if err := uv.syncRole(r.Context(), u.UserID, username, ldapRole); err != nil {
+		log.Error(op, "failed to sync user role", sl.Err(err))
+		return 0, errors.New("failed to sync user role: " + err.Error())
+	}
+
+	// Запреты по группам LDAP, как и роль, обновляются при каждом входе
+	if err := uv.syncDeniedPermissions(r.Context(), u.UserID, username); err != nil {
+		log.Error(op, "failed to sync denied permissions", sl.Err(err))
+		return 0, errors.New("failed to sync denied permissions: " + err.Error())
 	}
 
-	err = u.GetRoleByUsername(uv.Storage, username)
+	err = u.GetRoleByUsername(r.Context(), uv.Storage.DB, username)
 	if err != nil {
 		log.Warn("failed to get user role", sl.Err(err))
 		return 0, errors.New("failed to get user role: " + err.Error())
@@ -86,6 +182,104 @@ func (uv *UserVerifier) ValidateUser(username, password string, r *http.Request)
 	return u.Role, nil
 }
 
+// syncRole sets main role of the existing user by LDAP groups. Roles set by admin outside the mapping are kept
+func (uv *UserVerifier) syncRole(ctx context.Context, userID int, username string, ldapRole int) error {
+	var u user.User
+	if err := u.GetRoleByUsername(ctx, uv.Storage.DB, username); err != nil {
+		return err
+	}
+	if u.Role == ldapRole || !uv.roleMapping().manages(u.Role) {
+		return nil
+	}
+
+	var ro role.Role
+	if _, err := ro.SetMainRole(ctx, uv.Storage, userID, ldapRole, role.SourceLDAP, role.SourceLDAP); err != nil {
+		return err
+	}
+	uv.Log.Info("user role changed by LDAP groups", slog.String("username", username), slog.Int("old_role", u.Role), slog.Int("new_role", ldapRole))
+
+	return nil
+}
+
+// syncDeniedPermissions stores permissions denied to the user by LDAP groups. They are checked by rbac on top of all user roles
+func (uv *UserVerifier) syncDeniedPermissions(ctx context.Context, userID int, username string) error {
+	denied, err := uv.ldapDeniedPermissions(username)
+	if err != nil {
+		return err
+	}
+
+	var ro role.Role
+	old, err := ro.GetDeniedPermissions(ctx, uv.Storage.DB, userID)
+	if err != nil {
+		return err
+	}
+	if slices.Equal(old, denied) {
+		return nil
+	}
+
+	if err := ro.SetDeniedPermissions(ctx, uv.Storage, userID, denied); err != nil {
+		return err
+	}
+	uv.Log.Info("user denied permissions changed by LDAP groups", slog.String("username", username), slog.Any("old", old), slog.Any("new", denied))
+
+	return nil
+}
+
+// IsClientActive reports the API client exists and is active
+func (uv *UserVerifier) IsClientActive(ctx context.Context, clientID string) (bool, error) {
+	const op = "lib.oauth.IsClientActive"
+
+	var c client.APIClient
+	if err := c.GetClient(ctx, uv.Storage.DB, clientID); err != nil {
+		if errors.Is(err, storageHandler.ErrClientDoesNotExist) {
+			return false, nil
+		}
+		uv.Log.Error(op, "failed to get api client", sl.Err(err))
+		return false, err
+	}
+
+	return c.IsActive, nil
+}
+
+// ValidateClient validates API client credentials returning requested or first allowed scope
+func (uv *UserVerifier) ValidateClient(ctx context.Context, clientID, clientSecret, scope string) (int, error) {
+	const op = "lib.oauth.ValidateClient"
+	log := uv.Log.With(slog.String("op", op))
+
+	var c client.APIClient
+	if err := c.GetClient(ctx, uv.Storage.DB, clientID); err != nil {
+		log.Warn("failed to get api client", sl.Err(err))
+		return 0, errors.New("invalid client")
+	}
+	if !c.IsActive {
+		log.Warn("api client is inactive", slog.String("client_id", clientID))
+		return 0, errors.New("invalid client")
+	}
+	if err := CompareClientSecret(c.SecretHash, clientSecret); err != nil {
+		log.Warn("wrong api client secret", slog.String("client_id", clientID))
+		return 0, errors.New("invalid client")
+	}
+	if len(c.Scopes) == 0 {
+		log.Warn("api client has no scopes", slog.String("client_id", clientID))
+		return 0, errors.New("invalid scope")
+	}
+
+	// Если scope не запрошен, выдается первый из разрешенных
+	requestedScope := c.Scopes[0]
+	if scope != "" {
+		var err error
+		requestedScope, err = strconv.Atoi(scope)
+		if err != nil || !slices.Contains(c.Scopes, requestedScope) {
+			log.Warn("api client scope is not allowed", slog.String("client_id", clientID), slog.String("scope", scope))
+			return 0, errors.New("invalid scope")
+		}
+	}
+
+	log.Info("api client " + clientID + " successfully validated")
+
+	return requestedScope, nil
+}
+
 // AddClaims provides additional claims to the token
 func (uv *UserVerifier) AddClaims(credential, tokenID string, scope int, r *http.Request) (map[string]int, error) {
 	const op = "lib.oauth.AddClaims"
@@ -95,11 +289,10 @@ func (uv *UserVerifier) AddClaims(credential, tokenID string, scope int, r *http
 
 	// Get user id
 	var u user.User
-	u.Role = roles.User
-	err := u.GetUserID(uv.Storage, credential)
+	err := u.GetUserID(r.Context(), uv.Storage.DB, credential)
 	if err != nil {
 		// Если ошибка не об отсутствии user_id, то выход по стнадартной ошибке БД
-		if !errors.As(err, &storageHandler.ErrUserIDDoesNotExist) {
+		if !errors.Is(err, storageHandler.ErrUserIDDoesNotExist) {
 			log.Error(op, "failed to get user id", sl.Err(err))
 			return claims, errors.New("token claims error: " + err.Error())
 		}
@@ -109,21 +302,13 @@ func (uv *UserVerifier) AddClaims(credential, tokenID string, scope int, r *http
 			log.Error(op, "failed to get user info", sl.Err(err))
 			return claims, errors.New("failed to get user info: " + err.Error())
 		}
-		isMemberOfGroupITR, err := uv.LDAPServer.IsUserMemberOf(credential, "KD Heads employees and specialists")
-		if err != nil {
-			log.Error(op, "failed to check is user member of group", sl.Err(err))
-			return claims, errors.New("token claims error: " + err.Error())
-		}
-		isMemberOfGroupRsrvDeny, err := uv.LDAPServer.IsUserMemberOf(credential, "ReservationDeny")
+		ldapRole, err := uv.ldapRole(credential)
 		if err != nil {
 			log.Error(op, "failed to check is user member of group", sl.Err(err))
 			return claims, errors.New("token claims error: " + err.Error())
 		}
-		if !isMemberOfGroupITR || isMemberOfGroupRsrvDeny {
-			u.Role = roles.UserWithOutReservation
-		}
 		// Если ошибка выше была об отсутствии user_id, то создаем user_id для пользователя и получаем его в u.UserID
-		if err := u.NewUser(uv.Storage, credential, userInfo[1], userInfo[2], userInfo[3], userInfo[4], userInfo[5], u.Role); err != nil {
+		if err := u.NewUser(r.Context(), uv.Storage.DB, credential, userInfo.Name, userInfo.Title, userInfo.Department, userInfo.Mobile, userInfo.Mail, ldapRole); err != nil {
 			log.Error(op, "failed to create user in postgres", sl.Err(err))
 			return claims, errors.New("token claims error: " + err.Error())
 		}
@@ -135,30 +320,44 @@ func (uv *UserVerifier) AddClaims(credential, tokenID string, scope int, r *http
 	return claims, nil
 }
 
-// ValidateTokenID validates token ID
-func (uv *UserVerifier) ValidateTokenID(credential, tokenID, refreshTokenID string) error {
-	const op = "lib.oauth.ValidateTokenID"
+// RotateTokenID replaces refresh token ID of the session. Reused refresh token revokes the session
+func (uv *UserVerifier) RotateTokenID(ctx context.Context, credential, sessionID, oldRefreshTokenID, tokenID, refreshTokenID string, grace time.Duration) (string, error) {
+	const op = "lib.oauth.RotateTokenID"
 	log := uv.Log.With(slog.String("op", op))
 
-	var refreshToken *user.RefreshToken
-	err := refreshToken.ValidateRefreshTokenID(uv.Storage, credential, refreshTokenID) // credential contains username
+	var session user.Session
+	currentTokenID, err := session.RotateRefreshTokenID(ctx, uv.Storage.DB, credential, sessionID, oldRefreshTokenID, tokenID, refreshTokenID, grace) // credential contains username
 	if err != nil {
-		log.Warn("token ID validation error", sl.Err(err))
-		return errors.New("token ID validation error: " + err.Error())
+		if errors.Is(err, storageHandler.ErrRefreshTokenReused) {
+			log.Warn("refresh token reuse detected, session revoked", slog.String("username", credential), slog.String("session_id", sessionID))
+		} else {
+			log.Warn("token ID validation error", sl.Err(err))
+		}
+		return "", errors.New("token ID validation error: " + err.Error())
 	}
 
-	log.Info("token ID successfully validated")
+	if currentTokenID != tokenID {
+		log.Info("token ID already rotated by parallel request", slog.String("session_id", sessionID))
+		return currentTokenID, nil
+	}
 
-	return nil
+	log.Info("token ID successfully rotated")
+
+	return currentTokenID, nil
 }
 
-// StoreTokenID saves the token id generated for the user
-func (uv *UserVerifier) StoreTokenID(credential, tokenID, refreshTokenID string) error {
+// StoreTokenID saves the session of the user device with the refresh token ID
+func (uv *UserVerifier) StoreTokenID(credential, sessionID, deviceID, tokenID, refreshTokenID string, r *http.Request) error {
 	const op = "lib.oauth.StoreTokenID"
 	log := uv.Log.With(slog.String("op", op))
 
-	var refreshToken *user.RefreshToken
-	err := refreshToken.StoreRefreshTokenID(uv.Storage, credential, refreshTokenID) // credential contains username
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	var session user.Session
+	err = session.NewSession(r.Context(), uv.Storage.DB, credential, sessionID, deviceID, tokenID, refreshTokenID, r.UserAgent(), ip) // credential contains username
 	if err != nil {
 		log.Error(op, "token ID storing error", sl.Err(err))
 		return errors.New("token ID storing error: " + err.Error())
@@ -168,3 +367,41 @@ func (uv *UserVerifier) StoreTokenID(credential, tokenID, refreshTokenID string)
 
 	return nil
 }
+
+// RevokeSession revokes the session and adds its access token to denylist
+func (uv *UserVerifier) RevokeSession(ctx context.Context, credential, sessionID string) error {
+	const op = "lib.oauth.RevokeSession"
+	log := uv.Log.With(slog.String("op", op))
+
+	var session user.Session
+	err := session.RevokeSession(ctx, uv.Storage.DB, credential, sessionID) // credential contains username
+	if err != nil {
+		log.Error("session revocation error", sl.Err(err))
+		return errors.New("session revocation error: " + err.Error())
+	}
+
+	if uv.TokenTTL != 0 {
+		var rt user.RevokedToken
+		if err := rt.DeleteOldRevokedTokens(ctx, uv.Storage.DB, time.Now().Add(-uv.TokenTTL)); err != nil {
+			log.Warn("failed to delete old revoked tokens", sl.Err(err))
+		}
+	}
+
+	log.Info("session successfully revoked")
+
+	return nil
+}
+
+// IsTokenRevoked checks the access token ID is in denylist
+func (uv *UserVerifier) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	const op = "lib.oauth.IsTokenRevoked"
+
+	var rt user.RevokedToken
+	isRevoked, err := rt.IsTokenRevoked(ctx, uv.Storage.DB, tokenID)
+	if err != nil {
+		uv.Log.Error(op, "token revocation check error", sl.Err(err))
+		return false, errors.New("token revocation check error: " + err.Error())
+	}
+
+	return isRevoked, nil
+}
@@ -0,0 +1,122 @@
+package postImage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"portal/internal/lib/imaging"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/news"
+	"portal/internal/structs/models"
+
+	"github.com/gofrs/uuid"
+)
+
+// FormImages returns images uploaded in multipart form. Files are read from "images" field in the order of upload,
+// the single "image" field is kept for old clients
+func FormImages(r *http.Request) []*multipart.FileHeader {
+	if r.MultipartForm == nil {
+		return nil
+	}
+
+	hdrs := append([]*multipart.FileHeader{}, r.MultipartForm.File["image"]...)
+	return append(hdrs, r.MultipartForm.File["images"]...)
+}
+
+// CheckImages checks all uploaded images before any changes are made
+func CheckImages(hdrs []*multipart.FileHeader) error {
+	for _, hdr := range hdrs {
+		if _, err := imaging.CheckUpload(hdr); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Filename, err)
+		}
+	}
+	return nil
+}
+
+// NewObjectName returns unique object name for the post image
+func NewObjectName(postID int) string {
+	// Имя уникально для каждого изображения, чтобы удаление и добавление отдельных фото не затрагивало остальные
+	return fmt.Sprintf("post_images/post%d_%s", postID, uuid.Must(uuid.NewV4()).String())
+}
+
+// Upload re-encodes image, uploads it with resized variants to MinIO and adds it to the end of post images.
+// tx must be a transaction: the post is locked until it ends
+func Upload(ctx context.Context, tx postgres.Querier, blobStore blob.BlobStore, postID int, hdr *multipart.FileHeader, altText string) (news.PostImage, error) {
+	const op = "lib.postImage.Upload"
+
+	data, err := imaging.ReadUpload(hdr)
+	if err != nil {
+		return news.PostImage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pi, err := store(ctx, tx, blobStore, postID, NewObjectName(postID), data, altText)
+	if err != nil {
+		return news.PostImage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pi, nil
+}
+
+// store re-encodes image, uploads it under the name with resized variants and adds it to the end of post images
+func store(ctx context.Context, tx postgres.Querier, blobStore blob.BlobStore, postID int, name string, data []byte, altText string) (news.PostImage, error) {
+	processed, err := imaging.Process(data)
+	if err != nil {
+		return news.PostImage{}, err
+	}
+
+	objects := map[string][]byte{name: processed.Original}
+	for _, v := range imaging.Variants {
+		objects[imaging.VariantName(name, v)] = processed.Variants[v.Name]
+	}
+
+	uploaded := make([]string, 0, len(objects))
+	removeUploaded := func() {
+		for _, n := range uploaded {
+			blobStore.Delete(ctx, n)
+		}
+	}
+
+	for objectName, payload := range objects {
+		err = blobStore.Put(ctx, models.Image{
+			Payload:     bytes.NewReader(payload),
+			Name:        objectName,
+			Size:        int64(len(payload)),
+			ContentType: processed.ContentType,
+		})
+		if err != nil {
+			removeUploaded()
+			return news.PostImage{}, err
+		}
+		uploaded = append(uploaded, objectName)
+	}
+
+	var pi news.PostImage
+	if err := pi.NewPostImage(ctx, tx, postID, name, altText); err != nil {
+		removeUploaded()
+		return news.PostImage{}, err
+	}
+
+	return pi, nil
+}
+
+// RemoveObjects deletes images with all their variants from the blob store. Objects which failed to delete
+// are left for the blob gc
+func RemoveObjects(ctx context.Context, blobStore blob.BlobStore, names []string) {
+	for _, name := range names {
+		for _, n := range ObjectNames(name) {
+			blobStore.Delete(ctx, n)
+		}
+	}
+}
+
+// ObjectNames returns names of the original object and all its variants
+func ObjectNames(name string) []string {
+	names := []string{name}
+	for _, v := range imaging.Variants {
+		names = append(names, imaging.VariantName(name, v))
+	}
+	return names
+}
@@ -0,0 +1,125 @@
+package postImage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"portal/internal/lib/imaging"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/news"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidUploadName  = errors.New("invalid upload name")
+	ErrAlreadyFinalized   = errors.New("image is already added to the post")
+	ErrUploadNotSupported = errors.New("direct uploads are not supported by the storage")
+)
+
+var uploadNameRe = regexp.MustCompile(`^post_images/post(\d+)_[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// PresignedUpload is a link for direct upload of the post image to MinIO with PUT method
+type PresignedUpload struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewPresignedUpload issues presigned PUT link for the new post image. After upload the image is added to the post by Finalize
+func NewPresignedUpload(ctx context.Context, blobStore blob.BlobStore, postID int) (PresignedUpload, error) {
+	const op = "lib.postImage.NewPresignedUpload"
+
+	putter, ok := blobStore.(blob.PresignPutter)
+	if !ok {
+		return PresignedUpload{}, fmt.Errorf("%s: %w", op, ErrUploadNotSupported)
+	}
+
+	name := NewObjectName(postID)
+	u, expiresAt, err := putter.PresignPut(ctx, name)
+	if err != nil {
+		return PresignedUpload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return PresignedUpload{Name: name, URL: u.String(), ExpiresAt: expiresAt}, nil
+}
+
+// Finalize checks the object uploaded by presigned link and adds it to the post.
+// The image is re-encoded with variants as usual upload, but bytes go between API and MinIO only, not from the client
+func Finalize(ctx context.Context, tx postgres.Querier, blobStore blob.BlobStore, postID int, name, altText string) (news.PostImage, error) {
+	const op = "lib.postImage.Finalize"
+
+	// Имя должно быть выдано для этого поста, иначе можно привязать чужой объект из бакета
+	match := uploadNameRe.FindStringSubmatch(name)
+	if match == nil || match[1] != strconv.Itoa(postID) {
+		return news.PostImage{}, fmt.Errorf("%s: %w", op, ErrInvalidUploadName)
+	}
+
+	var pi news.PostImage
+	names, err := pi.GetObjectNamesByPostID(ctx, tx, postID)
+	if err != nil {
+		return news.PostImage{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if slices.Contains(names, name) {
+		return news.PostImage{}, fmt.Errorf("%s: %w", op, ErrAlreadyFinalized)
+	}
+
+	data, err := readUploaded(ctx, blobStore, name)
+	if err != nil {
+		// Непрошедший проверку объект больше не нужен
+		if !errors.Is(err, storageHandler.ErrImageDoesNotExist) {
+			blobStore.Delete(ctx, name)
+		}
+		return news.PostImage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pi, err = store(ctx, tx, blobStore, postID, name, data, altText)
+	if err != nil {
+		return news.PostImage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pi, nil
+}
+
+// readUploaded checks size and format of uploaded object before reading it whole
+func readUploaded(ctx context.Context, blobStore blob.BlobStore, name string) ([]byte, error) {
+	info, err := blobStore.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size > imaging.MaxSize {
+		return nil, imaging.ErrSizeOutOfLimit
+	}
+
+	object, err := blobStore.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	// Формат проверяется по первым байтам до чтения всего файла
+	header := make([]byte, 12)
+	n, err := io.ReadFull(object, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, imaging.ErrFormatNotAllowed
+	}
+	if _, err := imaging.Sniff(header[:n]); err != nil {
+		return nil, err
+	}
+
+	rest, err := io.ReadAll(io.LimitReader(object, imaging.MaxSize+1-int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	data := append(header[:n], rest...)
+	if int64(len(data)) > imaging.MaxSize {
+		return nil, imaging.ErrSizeOutOfLimit
+	}
+
+	return data, nil
+}
@@ -0,0 +1,82 @@
+package rbac
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/role"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type contextKey string
+
+const PermissionsContext contextKey = "rbac.permissions"
+
+// Authorizer checks permissions of the authorized user. Must be used after oauth.Authorize middleware
+type Authorizer struct {
+	storage *postgres.Storage
+	log     *slog.Logger
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) *Authorizer {
+	return &Authorizer{storage: storage, log: log}
+}
+
+// RequirePermission returns middleware allowing request only if one of the user roles has the permission
+func (a *Authorizer) RequirePermission(permission string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "lib.rbac.RequirePermission"
+
+			log := a.log.With(
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("permission", permission),
+			)
+
+			ps, err := a.permissions(r)
+			if err != nil {
+				log.Error("failed to get permissions", sl.Err(err))
+				w.WriteHeader(500)
+				render.JSON(w, r, resp.Error("failed to get permissions"))
+				return
+			}
+
+			//  Проверяем доступно ли действие для ролей текущего пользователя
+			if !slices.Contains(ps, permission) {
+				log.Error("access was denied")
+				w.WriteHeader(403)
+				render.JSON(w, r, resp.Error("access was denied"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), PermissionsContext, ps)))
+		})
+	}
+}
+
+// permissions returns permissions already resolved for the request or gets them from DB.
+// API client token has no user, its permissions are defined by the token scope
+func (a *Authorizer) permissions(r *http.Request) ([]string, error) {
+	if ps, ok := r.Context().Value(PermissionsContext).([]string); ok {
+		return ps, nil
+	}
+
+	var ro role.Role
+	credential, _ := r.Context().Value(oauth.CredentialContext).(string)
+	if strings.HasPrefix(credential, oauth.ClientCredentialPrefix) {
+		scope, _ := r.Context().Value(oauth.ScopeContext).(int)
+		return ro.GetPermissionsByRoleID(r.Context(), a.storage.DB, scope)
+	}
+
+	return ro.GetPermissionsByUsername(r.Context(), a.storage.DB, credential)
+}
@@ -1,20 +1,64 @@
 package vc
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"sync/atomic"
+	"time"
+
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/counter"
 )
 
+// ViewsCounter counts views not yet flushed to site_counter
 type ViewsCounter struct {
 	n int64
 }
 
-// Count returns the number of connections at the time
-// the call.
+// Count returns the number of views not flushed to DB yet
 func (vc *ViewsCounter) Count() int {
 	return int(atomic.LoadInt64(&vc.n))
 }
 
-// Add adds c to the number of active connections.
+// Add adds c to the number of views.
 func (vc *ViewsCounter) Add(c int64) {
 	atomic.AddInt64(&vc.n, c)
 }
+
+// Flush adds counted views to the total views counter in DB and resets the count.
+// On error views are returned to the counter for the next flush
+func (vc *ViewsCounter) Flush(ctx context.Context, storage *postgres.Storage) error {
+	const op = "lib.vc.Flush"
+
+	n := atomic.SwapInt64(&vc.n, 0)
+	if n == 0 {
+		return nil
+	}
+
+	var sc counter.SiteCounter
+	if err := sc.IncreaseCounter(ctx, storage.DB, counter.TotalViews, n); err != nil {
+		vc.Add(n)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Run flushes views every interval until ctx is done. The rest should be flushed by Flush at shutdown
+func (vc *ViewsCounter) Run(ctx context.Context, storage *postgres.Storage, log *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := vc.Flush(ctx, storage); err != nil {
+				log.Error("failed to flush views", sl.Err(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"portal/internal/structs/models"
+	"time"
+)
+
+var ErrPresignNotSupported = errors.New("presigned urls are not supported by the storage")
+
+// ObjectInfo describes stored object
+type ObjectInfo struct {
+	Name         string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// BlobStore stores images and other files. Missing object is reported as storageHandler.ErrImageDoesNotExist
+type BlobStore interface {
+	Put(ctx context.Context, image models.Image) error
+	// Get returns object content. It must be closed after use
+	Get(ctx context.Context, name string) (io.ReadSeekCloser, error)
+	// Delete removes the object. Deleting missing object is not an error
+	Delete(ctx context.Context, name string) error
+	Stat(ctx context.Context, name string) (ObjectInfo, error)
+	// List returns objects with names starting with prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// PresignGet returns short-lived link for direct download or ErrPresignNotSupported
+	PresignGet(ctx context.Context, name string) (*url.URL, time.Time, error)
+}
+
+// PresignPutter is implemented by stores which accept direct uploads by presigned link
+type PresignPutter interface {
+	PresignPut(ctx context.Context, name string) (*url.URL, time.Time, error)
+}
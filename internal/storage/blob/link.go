@@ -0,0 +1,20 @@
+package blob
+
+import "net/url"
+
+// imageLinkPrefix is the link to /api/image written to post_image.path and user.image_path
+const imageLinkPrefix = "https://corp-portal.kama-diesel.ru/api/image?name="
+
+// ImageLink returns link to the object served by /api/image. DB stores links, not object names,
+// so clients use image paths of posts and avatars as is
+func ImageLink(name string) string {
+	return imageLinkPrefix + name
+}
+
+// ObjectName extracts object name from the link made by ImageLink. Path without name parameter is returned as is
+func ObjectName(path string) string {
+	if u, err := url.Parse(path); err == nil && u.Query().Has("name") {
+		return u.Query().Get("name")
+	}
+	return path
+}
@@ -0,0 +1,25 @@
+package blob
+
+import "testing"
+
+func TestObjectName(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "image link", path: ImageLink("avatars/user1_10_256.jpg"), want: "avatars/user1_10_256.jpg"},
+		{name: "post image link", path: "https://corp-portal.kama-diesel.ru/api/image?name=post_images/post1_image1", want: "post_images/post1_image1"},
+		{name: "object name", path: "avatars/user1_10_256.jpg", want: "avatars/user1_10_256.jpg"},
+		{name: "external link", path: "https://example.com/item.png", want: "https://example.com/item.png"},
+		{name: "empty", path: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ObjectName(tt.path); got != tt.want {
+				t.Errorf("ObjectName(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package ldapServer
+
+import (
+	"strings"
+
+	"github.com/go-ldap/ldap"
+)
+
+// Значения из запросов пользователей попадают в фильтр только через Eq, который экранирует спецсимволы,
+// иначе ввод вида "*)(|(x=*" меняет смысл запроса
+
+// Eq returns (attr=value) filter with escaped value
+func Eq(attr, value string) string {
+	return "(" + attr + "=" + ldap.EscapeFilter(value) + ")"
+}
+
+// Present returns (attr=*) filter
+func Present(attr string) string {
+	return "(" + attr + "=*)"
+}
+
+// And returns (&...) filter
+func And(filters ...string) string {
+	return "(&" + strings.Join(filters, "") + ")"
+}
+
+// Or returns (|...) filter
+func Or(filters ...string) string {
+	return "(|" + strings.Join(filters, "") + ")"
+}
+
+// Not returns (!...) filter
+func Not(filter string) string {
+	return "(!" + filter + ")"
+}
+
+// extensibleMatch returns (attr:rule) filter. Rule is taken from config as is (e.g. 1.2.840.113556.1.4.803:=2)
+func extensibleMatch(attr, rule string) string {
+	return "(" + attr + ":" + rule + ")"
+}
+
+// userFilter finds enabled person by sAMAccountName
+func (ldapsrv *LDAPServer) userFilter(username string) string {
+	filters := []string{Eq("objectCategory", "Person"), Eq("sAMAccountName", username)}
+	if ldapsrv.UserAccountControl != "" {
+		filters = append(filters, Not(extensibleMatch("UserAccountControl", ldapsrv.UserAccountControl)))
+	}
+	return And(filters...)
+}
+
+// memberOfFilter finds user by sAMAccountName if the user is a member of the group from Users container
+func (ldapsrv *LDAPServer) memberOfFilter(username, group string) string {
+	return And(Eq("sAMAccountName", username), Eq("memberof", "CN="+group+",CN=Users,"+ldapsrv.BaseDN))
+}
@@ -0,0 +1,100 @@
+package ldapServer
+
+import (
+	"testing"
+)
+
+func TestEq(t *testing.T) {
+	tests := []struct {
+		name  string
+		attr  string
+		value string
+		want  string
+	}{
+		{name: "plain", attr: "sAMAccountName", value: "ivanov", want: "(sAMAccountName=ivanov)"},
+		{name: "empty", attr: "mail", value: "", want: "(mail=)"},
+		{name: "asterisk", attr: "sAMAccountName", value: "*", want: `(sAMAccountName=\2a)`},
+		{name: "parentheses", attr: "cn", value: "a(b)", want: `(cn=a\28b\29)`},
+		{name: "backslash", attr: "cn", value: `a\b`, want: `(cn=a\5cb)`},
+		{name: "nul", attr: "cn", value: "a\x00b", want: `(cn=a\00b)`},
+		{name: "injection", attr: "sAMAccountName", value: "*)(|(objectClass=*", want: `(sAMAccountName=\2a\29\28|\28objectClass=\2a)`},
+		// Байты UTF-8 вне ASCII тоже экранируются, AD сравнивает их так же
+		{name: "cyrillic", attr: "cn", value: "Ян", want: `(cn=\d0\af\d0\bd)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Eq(tt.attr, tt.value); got != tt.want {
+				t.Errorf("Eq(%q, %q) = %q, want %q", tt.attr, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterBuilder(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{name: "present", filter: Present("mail"), want: "(mail=*)"},
+		{name: "and", filter: And(Eq("a", "1"), Eq("b", "2")), want: "(&(a=1)(b=2))"},
+		{name: "or", filter: Or(Eq("a", "1"), Present("b")), want: "(|(a=1)(b=*))"},
+		{name: "not", filter: Not(Eq("a", "1")), want: "(!(a=1))"},
+		{name: "nested", filter: And(Or(Eq("a", "*"), Eq("b", "2")), Not(Present("c"))), want: `(&(|(a=\2a)(b=2))(!(c=*)))`},
+		{name: "extensible match", filter: extensibleMatch("UserAccountControl", "1.2.840.113556.1.4.803:=2"), want: "(UserAccountControl:1.2.840.113556.1.4.803:=2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.filter != tt.want {
+				t.Errorf("filter = %q, want %q", tt.filter, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserFilter(t *testing.T) {
+	tests := []struct {
+		name               string
+		userAccountControl string
+		username           string
+		want               string
+	}{
+		{
+			name:     "without account control",
+			username: "ivanov",
+			want:     "(&(objectCategory=Person)(sAMAccountName=ivanov))",
+		},
+		{
+			name:               "with account control",
+			userAccountControl: "1.2.840.113556.1.4.803:=2",
+			username:           "ivanov",
+			want:               "(&(objectCategory=Person)(sAMAccountName=ivanov)(!(UserAccountControl:1.2.840.113556.1.4.803:=2)))",
+		},
+		{
+			name:     "escaped username",
+			username: "iv*",
+			want:     `(&(objectCategory=Person)(sAMAccountName=iv\2a))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ldapsrv := &LDAPServer{UserAccountControl: tt.userAccountControl}
+			if got := ldapsrv.userFilter(tt.username); got != tt.want {
+				t.Errorf("userFilter(%q) = %q, want %q", tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemberOfFilter(t *testing.T) {
+	ldapsrv := &LDAPServer{BaseDN: "DC=corp,DC=local"}
+
+	got := ldapsrv.memberOfFilter("ivanov", "ReservationDeny")
+	want := "(&(sAMAccountName=ivanov)(memberof=CN=ReservationDeny,CN=Users,DC=corp,DC=local))"
+	if got != want {
+		t.Errorf("memberOfFilter() = %q, want %q", got, want)
+	}
+}
@@ -1,55 +1,293 @@
 package ldapServer
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-ldap/ldap"
 )
 
+// Режимы защиты соединения с LDAP сервером
+const (
+	SecurityNone     = "none"
+	SecurityStartTLS = "starttls"
+	SecurityLDAPS    = "ldaps"
+)
+
+const (
+	defaultPoolSize = 4
+	defaultTimeout  = 10 * time.Second
+)
+
+// ConnConfig defines connection to LDAP server. Zero value means plain ldap on port 389
+type ConnConfig struct {
+	Security           string // none, starttls или ldaps
+	Port               int    // По умолчанию 389, для ldaps 636
+	CAPath             string // PEM файл с сертификатом CA, по умолчанию системные сертификаты
+	InsecureSkipVerify bool
+	PoolSize           int
+	Timeout            time.Duration
+}
+
+// LDAPServer holds the pool of connections bound as service account. Passwords of users are checked
+// on separate short-lived connections, so user binds never change identity of the pooled connections
 type LDAPServer struct {
-	LDAPConn           *ldap.Conn
 	FQDN               string
 	BaseDN             string
 	UserAccountControl string
+
+	addr        string
+	security    string
+	tlsConfig   *tls.Config
+	timeout     time.Duration
+	srvUsername string
+	srvPassword string
+	pool        chan *ldap.Conn
 }
 
-func New(fqdn, baseDN, userAccountControl string) (*LDAPServer, error) {
+func New(fqdn, baseDN, userAccountControl string, connCfg ConnConfig) (*LDAPServer, error) {
 	const op = "storage.ldapServer.New" // Имя текущей функции для логов и ошибок
 
+	srvUsername, exists := os.LookupEnv("LDAP_USERNAME")
+	if !exists {
+		return nil, fmt.Errorf("%s: username for LDAP does not exists in env", op)
+	}
+	srvPassword, exists := os.LookupEnv("LDAP_PASSWORD")
+	if !exists {
+		return nil, fmt.Errorf("%s: password for LDAP does not exists in env", op)
+	}
+
+	ldapsrv := &LDAPServer{
+		FQDN:               fqdn,
+		BaseDN:             baseDN,
+		UserAccountControl: userAccountControl,
+		security:           connCfg.Security,
+		timeout:            connCfg.Timeout,
+		srvUsername:        srvUsername,
+		srvPassword:        srvPassword,
+	}
+	if ldapsrv.security == "" {
+		ldapsrv.security = SecurityNone
+	}
+	if ldapsrv.timeout == 0 {
+		ldapsrv.timeout = defaultTimeout
+	}
+
+	port := connCfg.Port
+	switch ldapsrv.security {
+	case SecurityNone, SecurityStartTLS:
+		if port == 0 {
+			port = 389
+		}
+	case SecurityLDAPS:
+		if port == 0 {
+			port = 636
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown security mode %s", op, ldapsrv.security)
+	}
 	// You can also use IP instead of FQDN
-	ldapConn, err := ldap.DialURL(fmt.Sprintf("ldap://%s:389", fqdn))
+	ldapsrv.addr = net.JoinHostPort(fqdn, strconv.Itoa(port))
+
+	if ldapsrv.security != SecurityNone {
+		ldapsrv.tlsConfig = &tls.Config{ServerName: fqdn, InsecureSkipVerify: connCfg.InsecureSkipVerify}
+		if connCfg.CAPath != "" {
+			rawCA, err := os.ReadFile(connCfg.CAPath)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			caPool := x509.NewCertPool()
+			if !caPool.AppendCertsFromPEM(rawCA) {
+				return nil, fmt.Errorf("%s: no certificates in %s", op, connCfg.CAPath)
+			}
+			ldapsrv.tlsConfig.RootCAs = caPool
+		}
+	}
+
+	poolSize := connCfg.PoolSize
+	if poolSize <= 0 {
+		poolSize = defaultPoolSize
+	}
+	ldapsrv.pool = make(chan *ldap.Conn, poolSize)
+
+	// Проверяем доступность сервера и учетные данные сервисной учетной записи при старте
+	conn, err := ldapsrv.dialService()
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	ldapsrv.put(conn)
 
-	return &LDAPServer{LDAPConn: ldapConn, FQDN: fqdn, BaseDN: baseDN, UserAccountControl: userAccountControl}, nil
+	return ldapsrv, nil
 }
 
-// Check user exists and get info in []string{userDN, name, position, department, mobile, mail}
-func (ldapsrv *LDAPServer) GetUserInfo(username string) ([]string, error) {
-	const op = "storage.ldapServer.GetUserInfo"
+// Close closes all pooled connections
+func (ldapsrv *LDAPServer) Close() {
+	for {
+		select {
+		case conn := <-ldapsrv.pool:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
 
-	srvUsername, exists := os.LookupEnv("LDAP_USERNAME")
-	if !exists {
-		return nil, fmt.Errorf("%s: username for LDAP does not exists in env", op)
+// dial opens new connection according to security mode
+func (ldapsrv *LDAPServer) dial() (*ldap.Conn, error) {
+	var conn *ldap.Conn
+	var err error
+	if ldapsrv.security == SecurityLDAPS {
+		conn, err = ldap.DialTLS("tcp", ldapsrv.addr, ldapsrv.tlsConfig)
+	} else {
+		conn, err = ldap.Dial("tcp", ldapsrv.addr)
 	}
-	srvPassword, exists := os.LookupEnv("LDAP_PASSWORD")
-	if !exists {
-		return nil, fmt.Errorf("%s: password for LDAP does not exists in env", op)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapsrv.timeout)
+
+	if ldapsrv.security == SecurityStartTLS {
+		if err := conn.StartTLS(ldapsrv.tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// dialService opens new connection bound as service account
+func (ldapsrv *LDAPServer) dialService() (*ldap.Conn, error) {
+	conn, err := ldapsrv.dial()
+	if err != nil {
+		return nil, err
 	}
+	if err := conn.Bind(ldapsrv.srvUsername, ldapsrv.srvPassword); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// get returns idle connection from the pool or opens new one
+func (ldapsrv *LDAPServer) get() (*ldap.Conn, error) {
+	for {
+		select {
+		case conn := <-ldapsrv.pool:
+			if conn.IsClosing() {
+				conn.Close()
+				continue
+			}
+			return conn, nil
+		default:
+			return ldapsrv.dialService()
+		}
+	}
+}
 
-	err := ldapsrv.LDAPConn.Bind(srvUsername, srvPassword)
-	if err != nil && ldap.IsErrorWithCode(err, 200) {
-		ldapsrv.LDAPConn, err = ldap.DialURL(fmt.Sprintf("ldap://%s:389", ldapsrv.FQDN))
+// put returns connection to the pool, extra connections are closed
+func (ldapsrv *LDAPServer) put(conn *ldap.Conn) {
+	if conn.IsClosing() {
+		conn.Close()
+		return
+	}
+	select {
+	case ldapsrv.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// isNetworkError reports the connection is broken and must not be reused
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return ldap.IsErrorWithCode(err, ldap.ErrorNetwork) || errors.As(err, &netErr)
+}
+
+// search runs request on pooled connection. Broken connection is dropped and request is retried once on new connection.
+// Non-zero pagingSize makes paged search returning all entries
+func (ldapsrv *LDAPServer) search(searchReq *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	for attempt := 0; ; attempt++ {
+		conn, err := ldapsrv.get()
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, err
 		}
-	} else if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+
+		var result *ldap.SearchResult
+		if pagingSize > 0 {
+			result, err = conn.SearchWithPaging(searchReq, pagingSize)
+		} else {
+			result, err = conn.Search(searchReq)
+		}
+		if err != nil && isNetworkError(err) {
+			conn.Close()
+			if attempt == 0 {
+				continue
+			}
+			return nil, err
+		}
+		ldapsrv.put(conn)
+
+		return result, err
 	}
+}
 
-	filter := fmt.Sprintf("(&(objectCategory=Person)(sAMAccountName=%s)(!(UserAccountControl:%s))!", username, ldapsrv.UserAccountControl)
+// Authenticate checks password of the user by bind on separate connection
+func (ldapsrv *LDAPServer) Authenticate(userDN, password string) error {
+	const op = "storage.ldapServer.Authenticate"
+
+	// Пустой пароль дает unauthenticated bind, который сервер считает успешным
+	if password == "" {
+		return fmt.Errorf("%s: empty password", op)
+	}
+
+	conn, err := ldapsrv.dial()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(userDN, password); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserInfo contains attributes of the user account
+type UserInfo struct {
+	DN         string
+	Name       string
+	Title      string
+	Department string
+	Mobile     string
+	Mail       string
+}
+
+// Атрибуты учетной записи, которые запрашиваются у сервера
+var userAttributes = []string{"name", "title", "department", "mobile", "mail"}
+
+// attributeValue returns the first value of the attribute or empty string. Attribute names are case insensitive
+func attributeValue(entry *ldap.Entry, attr string) string {
+	for _, a := range entry.Attributes {
+		if strings.EqualFold(a.Name, attr) && len(a.Values) != 0 {
+			return a.Values[0]
+		}
+	}
+	return ""
+}
+
+// Check user exists and get info
+func (ldapsrv *LDAPServer) GetUserInfo(username string) (UserInfo, error) {
+	const op = "storage.ldapServer.GetUserInfo"
 
 	searchReq := ldap.NewSearchRequest(
 		ldapsrv.BaseDN,
@@ -58,87 +296,95 @@ func (ldapsrv *LDAPServer) GetUserInfo(username string) ([]string, error) {
 		0,
 		0,
 		false,
-		filter,
-		[]string{"Name", "Title", "Department"},
+		ldapsrv.userFilter(username),
+		userAttributes,
 		nil,
 	)
-	result, err := ldapsrv.LDAPConn.Search(searchReq)
-	if err != nil && ldap.IsErrorWithCode(err, 200) {
-		ldapsrv.LDAPConn, err = ldap.DialURL(fmt.Sprintf("ldap://%s:389", ldapsrv.FQDN))
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-	} else if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	result, err := ldapsrv.search(searchReq, 0)
+	if err != nil {
+		return UserInfo{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if len(result.Entries) == 0 {
-		return nil, fmt.Errorf("%s: empty search result", op)
+		return UserInfo{}, fmt.Errorf("%s: empty search result", op)
 	}
 
-	userInfo := []string{result.Entries[0].DN}
-
-	name := result.Entries[0].GetAttributeValues("name")
-	if len(name) != 0 {
-		userInfo = append(userInfo, name[0])
-	} else {
-		userInfo = append(userInfo, "")
-	}
+	entry := result.Entries[0]
+	return UserInfo{
+		DN:         entry.DN,
+		Name:       attributeValue(entry, "name"),
+		Title:      attributeValue(entry, "title"),
+		Department: attributeValue(entry, "department"),
+		Mobile:     attributeValue(entry, "mobile"),
+		Mail:       attributeValue(entry, "mail"),
+	}, nil
+}
 
-	title := result.Entries[0].GetAttributeValues("title")
-	if len(title) != 0 {
-		userInfo = append(userInfo, title[0])
-	} else {
-		userInfo = append(userInfo, "")
-	}
+// GetThumbnailPhoto returns raw thumbnailPhoto of the user. Empty slice means the photo is not set
+func (ldapsrv *LDAPServer) GetThumbnailPhoto(username string) ([]byte, error) {
+	const op = "storage.ldapServer.GetThumbnailPhoto"
 
-	department := result.Entries[0].GetAttributeValues("department")
-	if len(department) != 0 {
-		userInfo = append(userInfo, department[0])
-	} else {
-		userInfo = append(userInfo, "")
-	}
-
-	mobile := result.Entries[0].GetAttributeValues("Mobile")
-	if len(mobile) != 0 {
-		userInfo = append(userInfo, mobile[0])
-	} else {
-		userInfo = append(userInfo, "")
+	searchReq := ldap.NewSearchRequest(
+		ldapsrv.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		ldapsrv.userFilter(username),
+		[]string{"thumbnailPhoto"},
+		nil,
+	)
+	result, err := ldapsrv.search(searchReq, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	mail := result.Entries[0].GetAttributeValues("mail")
-	if len(mail) != 0 {
-		userInfo = append(userInfo, mail[0])
-	} else {
-		userInfo = append(userInfo, "")
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("%s: empty search result", op)
 	}
 
-	return userInfo, nil
+	return result.Entries[0].GetRawAttributeValue("thumbnailPhoto"), nil
 }
 
 func (ldapsrv *LDAPServer) IsUserMemberOf(username, group string) (bool, error) {
 	const op = "storage.ldapServer.IsUserMemberOf"
 
-	srvUsername, exists := os.LookupEnv("LDAP_USERNAME")
-	if !exists {
-		return false, fmt.Errorf("%s: username for LDAP does not exists in env", op)
-	}
-	srvPassword, exists := os.LookupEnv("LDAP_PASSWORD")
-	if !exists {
-		return false, fmt.Errorf("%s: password for LDAP does not exists in env", op)
+	searchReq := ldap.NewSearchRequest(
+		ldapsrv.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		ldapsrv.memberOfFilter(username, group),
+		[]string{"dn"},
+		nil,
+	)
+	result, err := ldapsrv.search(searchReq, 0)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err := ldapsrv.LDAPConn.Bind(srvUsername, srvPassword)
-	if err != nil && ldap.IsErrorWithCode(err, 200) {
-		ldapsrv.LDAPConn, err = ldap.DialURL(fmt.Sprintf("ldap://%s:389", ldapsrv.FQDN))
-		if err != nil {
-			return false, fmt.Errorf("%s: %w", op, err)
-		}
-	} else if err != nil {
-		return false, fmt.Errorf("%s: %w", op, err)
+	if len(result.Entries) == 0 {
+		return false, nil
 	}
 
-	filter := fmt.Sprintf("(&(sAMAccountName=%s)(memberof=CN=%s,CN=Users,%s))", username, group, ldapsrv.BaseDN)
+	return true, nil
+}
+
+// DirectoryUser is the user account found during directory sync
+type DirectoryUser struct {
+	UserInfo
+	Username  string
+	Chief     string // Имя руководителя из атрибута manager
+	ManagerDN string
+	IsActive  bool
+}
+
+// ListUsers returns all user accounts under BaseDN including disabled ones
+func (ldapsrv *LDAPServer) ListUsers(pagingSize uint32) ([]DirectoryUser, error) {
+	const op = "storage.ldapServer.ListUsers"
 
 	searchReq := ldap.NewSearchRequest(
 		ldapsrv.BaseDN,
@@ -147,23 +393,53 @@ func (ldapsrv *LDAPServer) IsUserMemberOf(username, group string) (bool, error)
 		0,
 		0,
 		false,
-		filter,
-		[]string{},
+		And(Eq("objectCategory", "Person"), Eq("objectClass", "user"), Present("sAMAccountName")),
+		append([]string{"sAMAccountName", "manager", "userAccountControl"}, userAttributes...),
 		nil,
 	)
-	result, err := ldapsrv.LDAPConn.Search(searchReq)
-	if err != nil && ldap.IsErrorWithCode(err, 200) {
-		ldapsrv.LDAPConn, err = ldap.DialURL(fmt.Sprintf("ldap://%s:389", ldapsrv.FQDN))
-		if err != nil {
-			return false, fmt.Errorf("%s: %w", op, err)
-		}
-	} else if err != nil {
-		return false, fmt.Errorf("%s: %w", op, err)
+	result, err := ldapsrv.search(searchReq, pagingSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if len(result.Entries) == 0 {
-		return false, nil
+	disabledFlag := ldapsrv.disabledFlag()
+	users := make([]DirectoryUser, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		uac, _ := strconv.ParseInt(attributeValue(entry, "userAccountControl"), 10, 64)
+		users = append(users, DirectoryUser{
+			UserInfo: UserInfo{
+				DN:         entry.DN,
+				Name:       attributeValue(entry, "name"),
+				Title:      attributeValue(entry, "title"),
+				Department: attributeValue(entry, "department"),
+				Mobile:     attributeValue(entry, "mobile"),
+				Mail:       attributeValue(entry, "mail"),
+			},
+			Username:  attributeValue(entry, "sAMAccountName"),
+			Chief:     commonName(attributeValue(entry, "manager")),
+			ManagerDN: attributeValue(entry, "manager"),
+			IsActive:  uac&disabledFlag == 0,
+		})
 	}
 
-	return true, nil
+	return users, nil
+}
+
+// disabledFlag returns userAccountControl bit of disabled accounts from the matching rule
+// of config (e.g. 1.2.840.113556.1.4.803:=2), by default ACCOUNTDISABLE
+func (ldapsrv *LDAPServer) disabledFlag() int64 {
+	_, rawFlag, found := strings.Cut(ldapsrv.UserAccountControl, ":=")
+	if flag, err := strconv.ParseInt(rawFlag, 10, 64); found && err == nil && flag != 0 {
+		return flag
+	}
+	return 2
+}
+
+// commonName returns value of the first RDN of the DN (CN=Иванов Иван,OU=... -> Иванов Иван)
+func commonName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return parsed.RDNs[0].Attributes[0].Value
 }
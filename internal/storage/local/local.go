@@ -0,0 +1,215 @@
+package localStorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
+	"portal/internal/structs/models"
+	"strings"
+	"time"
+)
+
+// LocalStorage keeps objects as files under the root directory. Object name "a/b" is stored as <root>/a/b
+type LocalStorage struct {
+	root string
+}
+
+var ErrInvalidName = errors.New("invalid object name")
+
+var _ blob.BlobStore = (*LocalStorage)(nil)
+
+func New(root string) (*LocalStorage, error) {
+	const op = "storage.localStorage.New"
+
+	if root == "" {
+		return nil, fmt.Errorf("%s: root directory is not set", op)
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &LocalStorage{root: root}, nil
+}
+
+// path returns file path of the object. Names leaving the root directory are rejected
+func (ls *LocalStorage) path(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, "/") || path.Clean(name) != name || strings.HasPrefix(name, "../") || name == ".." {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(ls.root, filepath.FromSlash(name)), nil
+}
+
+// Put writes object to a temporary file and renames it, so readers never see partially written file
+func (ls *LocalStorage) Put(ctx context.Context, image models.Image) error {
+	const op = "storage.localStorage.Put"
+
+	p, err := ls.path(image.Name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, image.Payload); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (ls *LocalStorage) Get(ctx context.Context, name string) (io.ReadSeekCloser, error) {
+	const op = "storage.localStorage.Get"
+
+	p, err := ls.path(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, storageHandler.ErrImageDoesNotExist)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return f, nil
+}
+
+func (ls *LocalStorage) Delete(ctx context.Context, name string) error {
+	const op = "storage.localStorage.Delete"
+
+	p, err := ls.path(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (ls *LocalStorage) Stat(ctx context.Context, name string) (blob.ObjectInfo, error) {
+	const op = "storage.localStorage.Stat"
+
+	p, err := ls.path(name)
+	if err != nil {
+		return blob.ObjectInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return blob.ObjectInfo{}, fmt.Errorf("%s: %w", op, storageHandler.ErrImageDoesNotExist)
+		}
+		return blob.ObjectInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if info.IsDir() {
+		return blob.ObjectInfo{}, fmt.Errorf("%s: %w", op, storageHandler.ErrImageDoesNotExist)
+	}
+
+	contentType, err := detectContentType(p)
+	if err != nil {
+		return blob.ObjectInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return objectInfo(name, info, contentType), nil
+}
+
+// List walks the root directory. Content type is not detected to avoid opening every file
+func (ls *LocalStorage) List(ctx context.Context, prefix string) ([]blob.ObjectInfo, error) {
+	const op = "storage.localStorage.List"
+
+	objects := []blob.ObjectInfo{}
+	err := filepath.WalkDir(ls.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Временные файлы незавершенных загрузок не являются объектами
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ls.root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, objectInfo(name, info, ""))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return objects, nil
+}
+
+// PresignGet is not supported, files are served by the API
+func (ls *LocalStorage) PresignGet(ctx context.Context, name string) (*url.URL, time.Time, error) {
+	return nil, time.Time{}, blob.ErrPresignNotSupported
+}
+
+// objectInfo builds object info. ETag is made of modification time and size like in static file servers
+func objectInfo(name string, info fs.FileInfo, contentType string) blob.ObjectInfo {
+	return blob.ObjectInfo{
+		Name:         name,
+		Size:         info.Size(),
+		ContentType:  contentType,
+		ETag:         fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+		LastModified: info.ModTime(),
+	}
+}
+
+func detectContentType(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(header[:n]), nil
+}
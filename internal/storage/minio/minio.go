@@ -3,18 +3,33 @@ package minioServer
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
 	"portal/internal/structs/models"
+	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+var (
+	_ blob.BlobStore     = (*MinioProvider)(nil)
+	_ blob.PresignPutter = (*MinioProvider)(nil)
+)
+
 type MinioProvider struct {
 	minioAuthData
 	client *minio.Client
+	// Время жизни подписанных ссылок на загрузку и скачивание. По умолчанию DefaultPresignTTL
+	PresignTTL time.Duration
 }
 
+const DefaultPresignTTL = 15 * time.Minute
+
 type minioAuthData struct {
 	url      string
 	user     string
@@ -61,9 +76,14 @@ func (m *MinioProvider) Connect() error {
 	return nil
 }
 
-// UploadImage - Отправляет файл в minio
-func (m *MinioProvider) UploadImage(ctx context.Context, image models.Image) error {
-	const op = "storage.minioServer.UploadImage"
+// Put - Отправляет файл в minio
+func (m *MinioProvider) Put(ctx context.Context, image models.Image) error {
+	const op = "storage.minioServer.Put"
+
+	contentType := image.ContentType
+	if contentType == "" {
+		contentType = fmt.Sprintf("image/%s", strings.TrimPrefix(image.Extension, "."))
+	}
 
 	_, err := m.client.PutObject(
 		ctx,
@@ -71,7 +91,7 @@ func (m *MinioProvider) UploadImage(ctx context.Context, image models.Image) err
 		image.Name,
 		image.Payload,
 		image.Size,
-		minio.PutObjectOptions{ContentType: fmt.Sprintf("image/%s", image.Extension)},
+		minio.PutObjectOptions{ContentType: contentType},
 	)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -80,31 +100,102 @@ func (m *MinioProvider) UploadImage(ctx context.Context, image models.Image) err
 	return nil
 }
 
-// DownloadImage - Возвращает файл из minio. Полученный файл надо close() после использования
-func (m *MinioProvider) DownloadImage(ctx context.Context, imageName string) (*minio.Object, error) {
-	const op = "storage.minioServer.DownloadImage"
+// Get - Возвращает файл из minio. Полученный файл надо close() после использования
+func (m *MinioProvider) Get(ctx context.Context, name string) (io.ReadSeekCloser, error) {
+	const op = "storage.minioServer.Get"
 
 	object, err := m.client.GetObject(
 		ctx,
 		m.bucket, // Константа с именем бакета
-		imageName,
+		name,
 		minio.GetObjectOptions{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// GetObject не обращается к серверу, отсутствие файла выясняется только при первом запросе
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("%s: %w", op, notExist(err))
+	}
+
 	return object, nil
 }
 
-// RemoveImage - Удаляет файл в minio.
-func (m *MinioProvider) RemoveImage(ctx context.Context, imageName string) error {
-	const op = "storage.minioServer.RemoveImage"
+// Stat - Возвращает информацию о файле в minio без его загрузки
+func (m *MinioProvider) Stat(ctx context.Context, name string) (blob.ObjectInfo, error) {
+	const op = "storage.minioServer.Stat"
+
+	info, err := m.client.StatObject(
+		ctx,
+		m.bucket,
+		name,
+		minio.StatObjectOptions{},
+	)
+	if err != nil {
+		return blob.ObjectInfo{}, fmt.Errorf("%s: %w", op, notExist(err))
+	}
+
+	return objectInfo(info), nil
+}
+
+// List - Возвращает файлы, имена которых начинаются с prefix
+func (m *MinioProvider) List(ctx context.Context, prefix string) ([]blob.ObjectInfo, error) {
+	const op = "storage.minioServer.List"
+
+	objects := []blob.ObjectInfo{}
+	for info := range m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, fmt.Errorf("%s: %w", op, info.Err)
+		}
+		objects = append(objects, objectInfo(info))
+	}
+
+	return objects, nil
+}
+
+func (m *MinioProvider) presignTTL() time.Duration {
+	if m.PresignTTL <= 0 {
+		return DefaultPresignTTL
+	}
+	return m.PresignTTL
+}
+
+// PresignGet - Возвращает временную ссылку на скачивание файла напрямую из minio
+func (m *MinioProvider) PresignGet(ctx context.Context, name string) (*url.URL, time.Time, error) {
+	const op = "storage.minioServer.PresignGet"
+
+	ttl := m.presignTTL()
+	u, err := m.client.PresignedGetObject(ctx, m.bucket, name, ttl, nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, time.Now().Add(ttl), nil
+}
+
+// PresignPut - Возвращает временную ссылку на загрузку файла напрямую в minio методом PUT
+func (m *MinioProvider) PresignPut(ctx context.Context, name string) (*url.URL, time.Time, error) {
+	const op = "storage.minioServer.PresignPut"
+
+	ttl := m.presignTTL()
+	u, err := m.client.PresignedPutObject(ctx, m.bucket, name, ttl)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, time.Now().Add(ttl), nil
+}
+
+// Delete - Удаляет файл в minio. Удаление отсутствующего файла не является ошибкой
+func (m *MinioProvider) Delete(ctx context.Context, name string) error {
+	const op = "storage.minioServer.Delete"
 
 	err := m.client.RemoveObject(
 		ctx,
 		m.bucket,
-		imageName,
+		name,
 		minio.RemoveObjectOptions{},
 	)
 	if err != nil {
@@ -113,3 +204,20 @@ func (m *MinioProvider) RemoveImage(ctx context.Context, imageName string) error
 
 	return nil
 }
+
+func notExist(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return storageHandler.ErrImageDoesNotExist
+	}
+	return err
+}
+
+func objectInfo(info minio.ObjectInfo) blob.ObjectInfo {
+	return blob.ObjectInfo{
+		Name:         info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+}
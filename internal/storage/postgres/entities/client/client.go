@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/postgres"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	qrNewClient    = `INSERT INTO api_client (client_id, "name", secret_hash, scopes, is_active, creation_date) VALUES ($1, $2, $3, $4, TRUE, CURRENT_TIMESTAMP);`
+	qrGetClient    = `SELECT "name", secret_hash, scopes, is_active, creation_date FROM api_client WHERE client_id = $1;`
+	qrGetClients   = `SELECT client_id, "name", scopes, is_active, creation_date FROM api_client ORDER BY creation_date;`
+	qrDeleteClient = `DELETE FROM api_client WHERE client_id = $1;`
+)
+
+// APIClient is a service principal authorized by client_credentials grant. Scopes are the roles the client may request
+type APIClient struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	SecretHash   string    `json:"-"`
+	Scopes       []int     `json:"scopes"`
+	IsActive     bool      `json:"is_active"`
+	CreationDate time.Time `json:"creation_date"`
+}
+
+func (c *APIClient) NewClient(ctx context.Context, db postgres.Querier, clientID, name, secretHash string, scopes []int) error {
+	const op = "storage.postgres.entities.client.NewClient"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrNewClient, clientID, name, secretHash, pq.Array(scopes))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *APIClient) GetClient(ctx context.Context, db postgres.Querier, clientID string) error {
+	const op = "storage.postgres.entities.client.GetClient"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var scopes pq.Int64Array
+	err := db.QueryRowContext(ctx, qrGetClient, clientID).Scan(&c.Name, &c.SecretHash, &scopes, &c.IsActive, &c.CreationDate)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storageHandler.ErrClientDoesNotExist)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	c.ClientID = clientID
+	c.Scopes = make([]int, 0, len(scopes))
+	for _, scope := range scopes {
+		c.Scopes = append(c.Scopes, int(scope))
+	}
+
+	return nil
+}
+
+func (c *APIClient) GetClients(ctx context.Context, db postgres.Querier) ([]APIClient, error) {
+	const op = "storage.postgres.entities.client.GetClients"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetClients)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	cs := []APIClient{}
+	for qrResult.Next() {
+		var c APIClient
+		var scopes pq.Int64Array
+		if err := qrResult.Scan(&c.ClientID, &c.Name, &scopes, &c.IsActive, &c.CreationDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		for _, scope := range scopes {
+			c.Scopes = append(c.Scopes, int(scope))
+		}
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+func (c *APIClient) DeleteClient(ctx context.Context, db postgres.Querier, clientID string) error {
+	const op = "storage.postgres.entities.client.DeleteClient"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteClient, clientID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
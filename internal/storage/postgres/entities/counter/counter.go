@@ -0,0 +1,99 @@
+package counter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"portal/internal/storage/postgres"
+	"time"
+)
+
+// Имена счетчиков в site_counter
+const (
+	TotalViews = "total_views"
+)
+
+const (
+	// Общее значение и значение за текущий день увеличиваются одним запросом
+	qrIncreaseCounter = `WITH total AS (
+							 INSERT INTO site_counter ("name", n) VALUES ($1, $2)
+							 ON CONFLICT ("name") DO UPDATE SET n = site_counter.n + EXCLUDED.n
+						 )
+						 INSERT INTO site_counter_daily ("name", "day", n) VALUES ($1, CURRENT_DATE, $2)
+						 ON CONFLICT ("name", "day") DO UPDATE SET n = site_counter_daily.n + EXCLUDED.n;`
+	qrGetCounter      = `SELECT n FROM site_counter WHERE "name" = $1;`
+	qrGetDailyCounter = `SELECT "day", n FROM site_counter_daily WHERE "name" = $1 AND $2 <= "day" AND "day" <= $3 ORDER BY "day";`
+)
+
+type SiteCounter struct {
+	Name string `json:"name"`
+	N    int64  `json:"n"`
+}
+
+type DailyCounter struct {
+	Day time.Time `json:"day"`
+	N   int64     `json:"n"`
+}
+
+// IncreaseCounter adds amount to the counter and to its value for today
+func (sc *SiteCounter) IncreaseCounter(ctx context.Context, db postgres.Querier, name string, amount int64) error {
+	const op = "storage.postgres.entities.counter.IncreaseCounter"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	if _, err := db.ExecContext(ctx, qrIncreaseCounter, name, amount); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetCounter sets value of the counter to sc. Counter which was never increased is zero
+func (sc *SiteCounter) GetCounter(ctx context.Context, db postgres.Querier, name string) error {
+	const op = "storage.postgres.entities.counter.GetCounter"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	sc.Name = name
+	err := db.QueryRowContext(ctx, qrGetCounter, name).Scan(&sc.N)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			sc.N = 0
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetDailyCounter returns values of the counter by days from from to to inclusive. Days without values are skipped
+func (dc *DailyCounter) GetDailyCounter(ctx context.Context, db postgres.Querier, name string, from, to time.Time) ([]DailyCounter, error) {
+	const op = "storage.postgres.entities.counter.GetDailyCounter"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetDailyCounter, name, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	dcs := []DailyCounter{}
+	for qrResult.Next() {
+		var d DailyCounter
+		if err := qrResult.Scan(&d.Day, &d.N); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		dcs = append(dcs, d)
+	}
+	if err := qrResult.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return dcs, nil
+}
@@ -0,0 +1,55 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"portal/internal/storage/postgres"
+)
+
+// Все ссылки на файлы в хранилище. Для post_image используется имя объекта, у старых записей его нет - берется путь
+const qrGetReferences = `SELECT 'post_image', post_image_id, COALESCE(object_name, "path") FROM post_image
+						 UNION ALL
+						 SELECT 'user', user_id, image_path FROM "user" WHERE COALESCE(image_path, '') <> ''
+						 UNION ALL
+						 SELECT 'item', item_id, photo_path FROM item WHERE COALESCE(photo_path, '') <> '';`
+
+// Таблицы, которые ссылаются на файлы в хранилище
+const (
+	TablePostImage = "post_image"
+	TableUser      = "user"
+	TableItem      = "item"
+)
+
+// Reference is a row which refers to the stored object by path or object name
+type Reference struct {
+	Table string `json:"table"`
+	ID    int    `json:"id"`
+	Path  string `json:"path"`
+}
+
+func (ref *Reference) GetReferences(ctx context.Context, db postgres.Querier) ([]Reference, error) {
+	const op = "storage.postgres.entities.media.GetReferences"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetReferences)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	refs := []Reference{}
+	for qrResult.Next() {
+		var r Reference
+		if err := qrResult.Scan(&r.Table, &r.ID, &r.Path); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		refs = append(refs, r)
+	}
+	if err := qrResult.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return refs, nil
+}
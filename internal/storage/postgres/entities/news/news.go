@@ -1,8 +1,12 @@
 package news
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/blob"
 	"portal/internal/storage/postgres"
 	"strconv"
 	"time"
@@ -25,14 +29,13 @@ const (
 	qrUpdateCommentIsChecked  = `UPDATE comment SET is_checked = TRUE WHERE comment_id = $1;`
 	qrUpdateViews             = `UPDATE post SET views = views + 1 WHERE post_id = $1;`
 	qrGetLikesAmountByPostID  = `SELECT likes_amount FROM likes_amount WHERE post_id = $1;`
-	qrGetImageNamesByPostID   = `SELECT "path" FROM post_image WHERE post_id = $1;`
+	qrGetImageNamesByPostID   = `SELECT "path" FROM post_image WHERE post_id = $1 ORDER BY position, post_image_id;`
 	qrGetTagsByPostID         = `SELECT tag_id, "name", background_color, text_color FROM post_tags WHERE post_id = $1;`
 	qrGetTags                 = `SELECT tag_id, "name", background_color, text_color FROM tag;`
 	qrNewTag                  = `INSERT INTO tag("name", background_color, text_color) VALUES ($1, $2, $3);`
 	qrNewLike                 = `INSERT INTO "like"(user_id, post_id) VALUES ($1, $2);`
 	qrNewComment              = `INSERT INTO "comment"(user_id, post_id, "text", creation_date, is_checked) VALUES ($1, $2, $3, CURRENT_TIMESTAMP, FALSE);`
 	qrNewPost                 = `INSERT INTO post(title, "text", creation_date, update_date, views) VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 0) RETURNING post_id;`
-	qrNewPostImage            = `INSERT INTO post_image(post_id, "path") VALUES ($1, $2);`
 	qrNewInPostTag            = `INSERT INTO in_post_tag(post_id, tag_id) VALUES ($1, $2);`
 	qrDeleteInPostTagByPostID = `DELETE FROM in_post_tag WHERE post_id = $1;`
 	qrDeleteComment           = `DELETE FROM comment WHERE comment_id = $1;`
@@ -41,6 +44,27 @@ const (
 	qrDeletePostImageByPostID = `DELETE FROM post_image WHERE post_id = $1;`
 )
 
+const (
+	// Блокировка поста сериализует изменения позиций его изображений: без нее параллельные загрузки
+	// получают одну и ту же позицию
+	qrLockPost = `SELECT post_id FROM post WHERE post_id = $1 FOR UPDATE;`
+	// Новое изображение добавляется в конец списка изображений поста
+	qrNewPostImage = `INSERT INTO post_image(post_id, "path", object_name, alt_text, position)
+					  VALUES ($1, $2, $3, $4, (SELECT COALESCE(MAX(position) + 1, 0) FROM post_image WHERE post_id = $1))
+					  RETURNING post_image_id, position;`
+	qrGetPostImagesByPostID = `SELECT post_image_id, post_id, "path", COALESCE(object_name, ''), COALESCE(alt_text, ''), position
+							   FROM post_image WHERE post_id = $1 ORDER BY position, post_image_id;`
+	qrGetPostImageForUpdate = `SELECT post_id, COALESCE(object_name, ''), position FROM post_image WHERE post_image_id = $1 FOR UPDATE;`
+	qrGetPostImagesCount    = `SELECT count(*) FROM post_image WHERE post_id = $1;`
+	qrUpdatePostImageAlt    = `UPDATE post_image SET alt_text = NULLIF($2, '') WHERE post_image_id = $1;`
+	qrSetPostImagePosition  = `UPDATE post_image SET position = $2 WHERE post_image_id = $1;`
+	// Сдвиг изображений между старой и новой позицией при перемещении: $2 - нижняя граница, $3 - верхняя, $4 - смещение
+	qrShiftPostImages     = `UPDATE post_image SET position = position + $4 WHERE post_id = $1 AND position BETWEEN $2 AND $3;`
+	qrDeletePostImage     = `DELETE FROM post_image WHERE post_image_id = $1;`
+	qrGetPostImageNames   = `SELECT object_name FROM post_image WHERE post_id = $1 AND object_name IS NOT NULL;`
+	qrShiftPostImagesDown = `UPDATE post_image SET position = position - 1 WHERE post_id = $1 AND position > $2;`
+)
+
 const (
 	postsPerPage = 100000 // количество записей на странице
 )
@@ -55,10 +79,13 @@ type Post struct {
 }
 
 // Also set created post id value to p.PostID
-func (p *Post) NewPost(storage *postgres.Storage, title, text string) error {
+func (p *Post) NewPost(ctx context.Context, db postgres.Querier, title, text string) error {
 	const op = "storage.postgres.entities.news.NewPost"
 
-	err := storage.DB.QueryRow(qrNewPost, title, text).Scan(&p.PostID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, qrNewPost, title, text).Scan(&p.PostID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -66,10 +93,13 @@ func (p *Post) NewPost(storage *postgres.Storage, title, text string) error {
 	return nil
 }
 
-func (p *Post) GetText(storage *postgres.Storage, postID int) error {
+func (p *Post) GetText(ctx context.Context, db postgres.Querier, postID int) error {
 	const op = "storage.postgres.entities.news.GetText"
 
-	err := storage.DB.QueryRow(qrGetPostText, postID).Scan(&p.Text)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, qrGetPostText, postID).Scan(&p.Text)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -77,10 +107,13 @@ func (p *Post) GetText(storage *postgres.Storage, postID int) error {
 	return nil
 }
 
-func (p *Post) UpdatePost(storage *postgres.Storage, title, text string, postID int) error {
+func (p *Post) UpdatePost(ctx context.Context, db postgres.Querier, title, text string, postID int) error {
 	const op = "storage.postgres.entities.news.UpdatePost"
 
-	_, err := storage.DB.Exec(qrUpdatePost, title, text, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdatePost, title, text, postID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -88,10 +121,13 @@ func (p *Post) UpdatePost(storage *postgres.Storage, title, text string, postID
 	return nil
 }
 
-func (p *Post) DeletePost(storage *postgres.Storage, postID int) error {
+func (p *Post) DeletePost(ctx context.Context, db postgres.Querier, postID int) error {
 	const op = "storage.postgres.entities.news.DeletePost"
 
-	_, err := storage.DB.Exec(qrDeletePost, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeletePost, postID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -99,10 +135,13 @@ func (p *Post) DeletePost(storage *postgres.Storage, postID int) error {
 	return nil
 }
 
-func (p *Post) AddView(storage *postgres.Storage, postID int) error {
+func (p *Post) AddView(ctx context.Context, db postgres.Querier, postID int) error {
 	const op = "storage.postgres.entities.news.AddView"
 
-	_, err := storage.DB.Exec(qrUpdateViews, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateViews, postID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -116,9 +155,12 @@ type PostsPage struct {
 }
 
 // Return slice of Article structs with empty values of Images and Tags
-func (p *Post) GetPostsPage(storage *postgres.Storage, tagsID []string, page int, createdAfter, createdBefore time.Time) ([]Post, error) {
+func (p *Post) GetPostsPage(ctx context.Context, db postgres.Querier, tagsID []string, page int, createdAfter, createdBefore time.Time) ([]Post, error) {
 	const op = "storage.postgres.entities.news.GetPostsPage"
 
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
 	var ps []Post
 
 	var qrResult *sql.Rows
@@ -139,7 +181,7 @@ func (p *Post) GetPostsPage(storage *postgres.Storage, tagsID []string, page int
 	// If there are no tags, get posts without filter
 	// Else get posts with filter
 	if len(tagsID) == 0 {
-		qrResult, err = storage.DB.Query(qrGetPostsPage, createdAfter, createdBefore, limit, offset)
+		qrResult, err = db.QueryContext(ctx, qrGetPostsPage, createdAfter, createdBefore, limit, offset)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -151,7 +193,7 @@ func (p *Post) GetPostsPage(storage *postgres.Storage, tagsID []string, page int
 		}
 
 		// Count posts amount to make MaxPage in pagination
-		if err := storage.DB.QueryRow(qrGetPostsAmount).Scan(&postsAmount); err != nil {
+		if err := db.QueryRowContext(ctx, qrGetPostsAmount).Scan(&postsAmount); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 	} else {
@@ -168,13 +210,13 @@ func (p *Post) GetPostsPage(storage *postgres.Storage, tagsID []string, page int
 		// Count posts amount to make MaxPage in pagination
 		qrGetPostsWithTagsAmount := `SELECT COUNT(post_id) FROM ( `
 		qrGetPostsWithTagsAmount += qrGetPostIDsByTags + `) AS TEMP_TABLE;`
-		if err := storage.DB.QueryRow(qrGetPostsWithTagsAmount, createdAfter, createdBefore).Scan(&postsAmount); err != nil {
+		if err := db.QueryRowContext(ctx, qrGetPostsWithTagsAmount, createdAfter, createdBefore).Scan(&postsAmount); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 		qrGetPostIDsByTags += ` LIMIT ` + strconv.Itoa(limit) + ` OFFSET ` + strconv.Itoa(offset) + `;`
 
 		// Get all post ID with filter
-		qrResult, err = storage.DB.Query(qrGetPostIDsByTags, createdAfter, createdBefore)
+		qrResult, err = db.QueryContext(ctx, qrGetPostIDsByTags, createdAfter, createdBefore)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -185,7 +227,7 @@ func (p *Post) GetPostsPage(storage *postgres.Storage, tagsID []string, page int
 			if err := qrResult.Scan(&p.PostID); err != nil {
 				return nil, fmt.Errorf("%s: %w", op, err)
 			}
-			if err := storage.DB.QueryRow(qrGetPostByID, p.PostID).Scan(&p.Title, &p.Text, &p.Views, &p.CreationDate, &p.UpdateDate); err != nil {
+			if err := db.QueryRowContext(ctx, qrGetPostByID, p.PostID).Scan(&p.Title, &p.Text, &p.Views, &p.CreationDate, &p.UpdateDate); err != nil {
 				return nil, fmt.Errorf("%s: %w", op, err)
 			}
 			ps = append(ps, *p)
@@ -209,12 +251,97 @@ type PostImage struct {
 	PostImageID int    `json:"post_image_id,omitempty"`
 	PostID      int    `json:"post_id,omitempty"`
 	Path        string `json:"path,omitempty"`
+	ObjectName  string `json:"-"`
+	AltText     string `json:"alt_text"`
+	Position    int    `json:"position"`
 }
 
-func (pi *PostImage) NewPostImage(storage *postgres.Storage, postID int, minioName string) error {
+// NewPostImage adds image to the end of post images. Also set created image id and position to pi.
+// The post is locked until the end of the transaction, so tx must be opened by the caller
+func (pi *PostImage) NewPostImage(ctx context.Context, tx postgres.Querier, postID int, minioName, altText string) error {
 	const op = "storage.postgres.entities.news.NewPostImage"
 
-	_, err := storage.DB.Exec(qrNewPostImage, postID, fmt.Sprintf("https://corp-portal.kama-diesel.ru/api/image?name=%s", minioName))
+	ctx, cancel := postgres.WithQueryTimeout(ctx, tx)
+	defer cancel()
+
+	if err := lockPost(ctx, tx, postID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	path := blob.ImageLink(minioName)
+	err := tx.QueryRowContext(ctx, qrNewPostImage, postID, path, minioName, sql.NullString{String: altText, Valid: altText != ""}).Scan(&pi.PostImageID, &pi.Position)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	pi.PostID = postID
+	pi.Path = path
+	pi.ObjectName = minioName
+	pi.AltText = altText
+
+	return nil
+}
+
+// GetPostImages returns images of the post ordered by position
+func (pi *PostImage) GetPostImages(ctx context.Context, db postgres.Querier, postID int) ([]PostImage, error) {
+	const op = "storage.postgres.entities.news.GetPostImages"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetPostImagesByPostID, postID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	pis := []PostImage{}
+	for qrResult.Next() {
+		var i PostImage
+		if err := qrResult.Scan(&i.PostImageID, &i.PostID, &i.Path, &i.ObjectName, &i.AltText, &i.Position); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		pis = append(pis, i)
+	}
+
+	return pis, nil
+}
+
+// GetObjectNamesByPostID returns MinIO object names of all post images
+func (pi *PostImage) GetObjectNamesByPostID(ctx context.Context, db postgres.Querier, postID int) ([]string, error) {
+	const op = "storage.postgres.entities.news.GetObjectNamesByPostID"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetPostImageNames, postID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	var names []string
+	for qrResult.Next() {
+		var name string
+		if err := qrResult.Scan(&name); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// UpdatePostImage sets alt text of the image and moves it to the position. Images between old and new positions are shifted.
+// Position out of range moves the image to the end
+func (pi *PostImage) UpdatePostImage(ctx context.Context, storage *postgres.Storage, postImageID, position int, altText string) error {
+	const op = "storage.postgres.entities.news.UpdatePostImage"
+
+	ctx, cancel := postgres.WithTxTimeout(ctx, storage)
+	defer cancel()
+
+	err := storage.WithTx(ctx, func(tx postgres.Querier) error {
+		return pi.MovePostImage(ctx, tx, postImageID, position, altText)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -222,10 +349,111 @@ func (pi *PostImage) NewPostImage(storage *postgres.Storage, postID int, minioNa
 	return nil
 }
 
-func (pi *PostImage) GetImageInfoByPostID(storage *postgres.Storage, postID int) ([]string, error) {
+// MovePostImage does the same as UpdatePostImage within the transaction tx opened by the caller,
+// so the image can be added and moved atomically
+func (pi *PostImage) MovePostImage(ctx context.Context, tx postgres.Querier, postImageID, position int, altText string) error {
+	const op = "storage.postgres.entities.news.MovePostImage"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, tx)
+	defer cancel()
+
+	var postID, oldPosition int
+	var objectName string
+	if err := tx.QueryRowContext(ctx, qrGetPostImageForUpdate, postImageID).Scan(&postID, &objectName, &oldPosition); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storageHandler.ErrPostImageDoesNotExist)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := lockPost(ctx, tx, postID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, qrGetPostImagesCount, postID).Scan(&count); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if position < 0 || position >= count {
+		position = count - 1
+	}
+
+	var err error
+	switch {
+	case position < oldPosition:
+		_, err = tx.ExecContext(ctx, qrShiftPostImages, postID, position, oldPosition-1, 1)
+	case position > oldPosition:
+		_, err = tx.ExecContext(ctx, qrShiftPostImages, postID, oldPosition+1, position, -1)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, qrSetPostImagePosition, postImageID, position); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, qrUpdatePostImageAlt, postImageID, altText); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	pi.PostImageID = postImageID
+	pi.PostID = postID
+	pi.ObjectName = objectName
+	pi.Position = position
+	pi.AltText = altText
+
+	return nil
+}
+
+// DeletePostImage deletes the image and closes the gap in positions. Also set post id and object name of deleted image to pi
+func (pi *PostImage) DeletePostImage(ctx context.Context, storage *postgres.Storage, postImageID int) error {
+	const op = "storage.postgres.entities.news.DeletePostImage"
+
+	ctx, cancel := postgres.WithTxTimeout(ctx, storage)
+	defer cancel()
+
+	err := storage.WithTx(ctx, func(tx postgres.Querier) error {
+		if err := tx.QueryRowContext(ctx, qrGetPostImageForUpdate, postImageID).Scan(&pi.PostID, &pi.ObjectName, &pi.Position); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storageHandler.ErrPostImageDoesNotExist
+			}
+			return err
+		}
+		if err := lockPost(ctx, tx, pi.PostID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, qrDeletePostImage, postImageID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, qrShiftPostImagesDown, pi.PostID, pi.Position)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	pi.PostImageID = postImageID
+
+	return nil
+}
+
+// lockPost locks the post row until the end of the transaction tx
+func lockPost(ctx context.Context, tx postgres.Querier, postID int) error {
+	if err := tx.QueryRowContext(ctx, qrLockPost, postID).Scan(&postID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storageHandler.ErrPostDoesNotExist
+		}
+		return err
+	}
+	return nil
+}
+
+func (pi *PostImage) GetImageInfoByPostID(ctx context.Context, db postgres.Querier, postID int) ([]string, error) {
 	const op = "storage.postgres.entities.news.GetImagePathsByPostID"
 
-	qrResult, err := storage.DB.Query(qrGetImageNamesByPostID, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetImageNamesByPostID, postID)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -243,10 +471,13 @@ func (pi *PostImage) GetImageInfoByPostID(storage *postgres.Storage, postID int)
 	return paths, nil
 }
 
-func (pi *PostImage) DeletePostImageByPostID(storage *postgres.Storage, postID int) error {
+func (pi *PostImage) DeletePostImageByPostID(ctx context.Context, db postgres.Querier, postID int) error {
 	const op = "storage.postgres.entities.news.DeletePostImageByPostID"
 
-	_, err := storage.DB.Exec(qrDeletePostImageByPostID, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeletePostImageByPostID, postID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -261,10 +492,13 @@ type Tag struct {
 	TextColor       string `json:"text_color,omitempty"`
 }
 
-func (t *Tag) NewTag(storage *postgres.Storage, name, backgroundColor, textColor string) error {
+func (t *Tag) NewTag(ctx context.Context, db postgres.Querier, name, backgroundColor, textColor string) error {
 	const op = "storage.postgres.entities.news.NewTag"
 
-	_, err := storage.DB.Exec(qrNewTag, name, backgroundColor, textColor)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrNewTag, name, backgroundColor, textColor)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -272,10 +506,13 @@ func (t *Tag) NewTag(storage *postgres.Storage, name, backgroundColor, textColor
 	return nil
 }
 
-func (t *Tag) UpdateTag(storage *postgres.Storage, tagID int, name, backgroundColor, textColor string) error {
+func (t *Tag) UpdateTag(ctx context.Context, db postgres.Querier, tagID int, name, backgroundColor, textColor string) error {
 	const op = "storage.postgres.entities.news.UpdateTag"
 
-	_, err := storage.DB.Exec(qrUpdateTag, name, backgroundColor, textColor, tagID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateTag, name, backgroundColor, textColor, tagID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -283,10 +520,13 @@ func (t *Tag) UpdateTag(storage *postgres.Storage, tagID int, name, backgroundCo
 	return nil
 }
 
-func (t *Tag) DeleteTag(storage *postgres.Storage, tagID int) error {
+func (t *Tag) DeleteTag(ctx context.Context, db postgres.Querier, tagID int) error {
 	const op = "storage.postgres.entities.news.DeleteTag"
 
-	_, err := storage.DB.Exec(qrDeleteTag, tagID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteTag, tagID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -294,10 +534,13 @@ func (t *Tag) DeleteTag(storage *postgres.Storage, tagID int) error {
 	return nil
 }
 
-func (t *Tag) GetTags(storage *postgres.Storage) ([]Tag, error) {
+func (t *Tag) GetTags(ctx context.Context, db postgres.Querier) ([]Tag, error) {
 	const op = "storage.postgres.entities.news.GetTags"
 
-	qrResult, err := storage.DB.Query(qrGetTags)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetTags)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -314,10 +557,13 @@ func (t *Tag) GetTags(storage *postgres.Storage) ([]Tag, error) {
 	return tags, nil
 }
 
-func (t *Tag) GetTagsByPostID(storage *postgres.Storage, postID int) ([]Tag, error) {
+func (t *Tag) GetTagsByPostID(ctx context.Context, db postgres.Querier, postID int) ([]Tag, error) {
 	const op = "storage.postgres.entities.news.GetTagsByPostID"
 
-	qrResult, err := storage.DB.Query(qrGetTagsByPostID, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetTagsByPostID, postID)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -340,10 +586,13 @@ type InPostTag struct {
 	PostID      int `json:"post_id,omitempty"`
 }
 
-func (ipt *InPostTag) NewInPostTag(storage *postgres.Storage, postID, tagID int) error {
+func (ipt *InPostTag) NewInPostTag(ctx context.Context, db postgres.Querier, postID, tagID int) error {
 	const op = "storage.postgres.entities.news.NewInPostTag"
 
-	_, err := storage.DB.Exec(qrNewInPostTag, postID, tagID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrNewInPostTag, postID, tagID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -351,10 +600,13 @@ func (ipt *InPostTag) NewInPostTag(storage *postgres.Storage, postID, tagID int)
 	return nil
 }
 
-func (ipt *InPostTag) DeleteInPostTagByPostID(storage *postgres.Storage, postID int) error {
+func (ipt *InPostTag) DeleteInPostTagByPostID(ctx context.Context, db postgres.Querier, postID int) error {
 	const op = "storage.postgres.entities.news.DeleteInPostTagByPostID"
 
-	_, err := storage.DB.Exec(qrDeleteInPostTagByPostID, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteInPostTagByPostID, postID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -367,10 +619,13 @@ type Like struct {
 	PostID int `json:"post_id,omitempty"`
 }
 
-func (l *Like) NewLike(storage *postgres.Storage, userID, postID int) error {
+func (l *Like) NewLike(ctx context.Context, db postgres.Querier, userID, postID int) error {
 	const op = "storage.postgres.entities.news.NewLike"
 
-	_, err := storage.DB.Exec(qrNewLike, userID, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrNewLike, userID, postID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -378,13 +633,16 @@ func (l *Like) NewLike(storage *postgres.Storage, userID, postID int) error {
 	return nil
 }
 
-func (l *Like) GetLikesAmount(storage *postgres.Storage, postID int) (int, error) {
+func (l *Like) GetLikesAmount(ctx context.Context, db postgres.Querier, postID int) (int, error) {
 	const op = "storage.postgres.entities.news.GetLikesAmount"
 
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
 	var amount int
 
 	// Запрашиваем кол-во лайков у поста в БД. Если лайки ещё не ставили или post_id нет, то вернётся 0
-	err := storage.DB.QueryRow(qrGetLikesAmountByPostID, postID).Scan(&amount)
+	err := db.QueryRowContext(ctx, qrGetLikesAmountByPostID, postID).Scan(&amount)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, nil
@@ -395,10 +653,13 @@ func (l *Like) GetLikesAmount(storage *postgres.Storage, postID int) (int, error
 	return amount, nil
 }
 
-func (l *Like) IsLikedByUserID(storage *postgres.Storage, postID, userID int) (bool, error) {
+func (l *Like) IsLikedByUserID(ctx context.Context, db postgres.Querier, postID, userID int) (bool, error) {
 	const op = "storage.postgres.entities.news.IsLikedByUserID"
 
-	qrResult, err := storage.DB.Query(qrGetIsLikedByUserID, postID, userID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetIsLikedByUserID, postID, userID)
 	if err != nil {
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
@@ -421,10 +682,13 @@ type Comment struct {
 	IsChecked    bool      `json:"is_checked,omitempty"`
 }
 
-func (c *Comment) NewComment(storage *postgres.Storage, text string, userID, postID int) error {
+func (c *Comment) NewComment(ctx context.Context, db postgres.Querier, text string, userID, postID int) error {
 	const op = "storage.postgres.entities.news.NewComment"
 
-	_, err := storage.DB.Exec(qrNewComment, userID, postID, text)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrNewComment, userID, postID, text)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -432,10 +696,13 @@ func (c *Comment) NewComment(storage *postgres.Storage, text string, userID, pos
 	return nil
 }
 
-func (c *Comment) GetCommentsByPostID(storage *postgres.Storage, postID int) ([]Comment, error) {
+func (c *Comment) GetCommentsByPostID(ctx context.Context, db postgres.Querier, postID int) ([]Comment, error) {
 	const op = "storage.postgres.entities.news.GetCommentsByPostID"
 
-	qrResult, err := storage.DB.Query(qrGetCommentsByPostID, postID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetCommentsByPostID, postID)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -452,10 +719,13 @@ func (c *Comment) GetCommentsByPostID(storage *postgres.Storage, postID int) ([]
 	return cs, nil
 }
 
-func (c *Comment) GetUncheckedComments(storage *postgres.Storage) ([]Comment, error) {
+func (c *Comment) GetUncheckedComments(ctx context.Context, db postgres.Querier) ([]Comment, error) {
 	const op = "storage.postgres.entities.news.GetUncheckedComments"
 
-	qrResult, err := storage.DB.Query(qrGetUncheckedComments)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetUncheckedComments)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -472,11 +742,14 @@ func (c *Comment) GetUncheckedComments(storage *postgres.Storage) ([]Comment, er
 	return cs, nil
 }
 
-func (c *Comment) GetCommentsAmount(storage *postgres.Storage, postID int) (int, error) {
+func (c *Comment) GetCommentsAmount(ctx context.Context, db postgres.Querier, postID int) (int, error) {
 	const op = "storage.postgres.entities.news.GetCommentsAmount"
 
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
 	var amount int
-	err := storage.DB.QueryRow(qrGetCommentsAmount, postID).Scan(&amount)
+	err := db.QueryRowContext(ctx, qrGetCommentsAmount, postID).Scan(&amount)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
@@ -484,10 +757,13 @@ func (c *Comment) GetCommentsAmount(storage *postgres.Storage, postID int) (int,
 	return amount, nil
 }
 
-func (c *Comment) UpdateCommentText(storage *postgres.Storage, commentID int, text string) error {
+func (c *Comment) UpdateCommentText(ctx context.Context, db postgres.Querier, commentID int, text string) error {
 	const op = "storage.postgres.entities.news.UpdateCommentText"
 
-	_, err := storage.DB.Exec(qrUpdateCommentText, text, commentID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateCommentText, text, commentID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -495,10 +771,13 @@ func (c *Comment) UpdateCommentText(storage *postgres.Storage, commentID int, te
 	return nil
 }
 
-func (c *Comment) UpdateCommentIsChecked(storage *postgres.Storage, commentID int) error {
+func (c *Comment) UpdateCommentIsChecked(ctx context.Context, db postgres.Querier, commentID int) error {
 	const op = "storage.postgres.entities.news.UpdateCommentIsChecked"
 
-	_, err := storage.DB.Exec(qrUpdateCommentIsChecked, commentID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateCommentIsChecked, commentID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -506,10 +785,13 @@ func (c *Comment) UpdateCommentIsChecked(storage *postgres.Storage, commentID in
 	return nil
 }
 
-func (c *Comment) DeleteComment(storage *postgres.Storage, commentID int) error {
+func (c *Comment) DeleteComment(ctx context.Context, db postgres.Querier, commentID int) error {
 	const op = "storage.postgres.entities.news.DeleteComment"
 
-	_, err := storage.DB.Exec(qrDeleteComment, commentID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteComment, commentID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -1,6 +1,7 @@
 package reservation
 
 import (
+	"context"
 	"fmt"
 	"portal/internal/storage/postgres"
 	"time"
@@ -32,10 +33,13 @@ type Locker struct {
 	Name     string `json:"name,omitempty"`
 }
 
-func (l *Locker) GetLockerName(storage *postgres.Storage, lockerID int) error {
+func (l *Locker) GetLockerName(ctx context.Context, db postgres.Querier, lockerID int) error {
 	const op = "storage.postgres.entities.reservation.GetLockerName"
 
-	qrResult, err := storage.DB.Query(qrGetNameByLockerID, lockerID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetNameByLockerID, lockerID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -59,10 +63,13 @@ type ActualLocker struct {
 	Finish      int  `json:"finish"`
 }
 
-func (al *ActualLocker) GetActualLockers(storage *postgres.Storage, start, finish time.Time) ([]ActualLocker, error) {
+func (al *ActualLocker) GetActualLockers(ctx context.Context, db postgres.Querier, start, finish time.Time) ([]ActualLocker, error) {
 	const op = "storage.postgres.entities.reservation.GetActualLockers"
 
-	qrResult, err := storage.DB.Query(qrGetActualLockers, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetActualLockers, start, finish)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -89,10 +96,13 @@ type LockerReservation struct {
 	UserID              int              `json:"user_id,omitempty"`
 }
 
-func (r *LockerReservation) HasUserLockerReservationInDateRange(storage *postgres.Storage, userID int, start, finish string) (bool, error) {
+func (r *LockerReservation) HasUserLockerReservationInDateRange(ctx context.Context, db postgres.Querier, userID int, start, finish string) (bool, error) {
 	const op = "storage.postgres.entities.reservation.HasUserLockerReservationInDateRange" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetUserLockerReservationInDateRange, userID, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetUserLockerReservationInDateRange, userID, start, finish)
 	if err != nil {
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
@@ -106,10 +116,13 @@ func (r *LockerReservation) HasUserLockerReservationInDateRange(storage *postgre
 	return true, nil
 }
 
-func (r *LockerReservation) InsertLockerReservation(storage *postgres.Storage, lockerID, userID int, start, finish string) error {
+func (r *LockerReservation) InsertLockerReservation(ctx context.Context, db postgres.Querier, lockerID, userID int, start, finish string) error {
 	const op = "storage.postgres.entities.reservation.InsertLockerReservation" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetIsLockerAvailable, lockerID, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetIsLockerAvailable, lockerID, start, finish)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -120,7 +133,7 @@ func (r *LockerReservation) InsertLockerReservation(storage *postgres.Storage, l
 		return fmt.Errorf("%s: locker is already taken", op)
 	}
 
-	_, err = storage.DB.Exec(qrInsertLockerReservation, lockerID, userID, start, finish)
+	_, err = db.ExecContext(ctx, qrInsertLockerReservation, lockerID, userID, start, finish)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -128,10 +141,13 @@ func (r *LockerReservation) InsertLockerReservation(storage *postgres.Storage, l
 	return nil
 }
 
-func (lr *LockerReservation) UpdateLockerReservation(storage *postgres.Storage, lockerReservationID, lockerID int, start, finish time.Time) error {
+func (lr *LockerReservation) UpdateLockerReservation(ctx context.Context, db postgres.Querier, lockerReservationID, lockerID int, start, finish time.Time) error {
 	const op = "storage.postgres.entities.reservation.UpdateLockerReservation" // Имя текущей функции для логов и ошибок
 
-	_, err := storage.DB.Exec(qrUpdateLockerReservation, lockerReservationID, lockerID, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateLockerReservation, lockerReservationID, lockerID, start, finish)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -139,10 +155,13 @@ func (lr *LockerReservation) UpdateLockerReservation(storage *postgres.Storage,
 	return nil
 }
 
-func (lr *LockerReservation) DeleteLockerReservation(storage *postgres.Storage, lockerReservationID int) error {
+func (lr *LockerReservation) DeleteLockerReservation(ctx context.Context, db postgres.Querier, lockerReservationID int) error {
 	const op = "storage.postgres.entities.reservation.DeleteLockerReservation" // Имя текущей функции для логов и ошибок
 
-	_, err := storage.DB.Exec(qrDeleteLockerReservation, lockerReservationID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteLockerReservation, lockerReservationID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -150,10 +169,13 @@ func (lr *LockerReservation) DeleteLockerReservation(storage *postgres.Storage,
 	return nil
 }
 
-func (lr *LockerReservation) GetLockerReservationsByUserID(storage *postgres.Storage, userID int) ([]LockerReservation, error) {
+func (lr *LockerReservation) GetLockerReservationsByUserID(ctx context.Context, db postgres.Querier, userID int) ([]LockerReservation, error) {
 	const op = "storage.postgres.entities.reservation.GetLockerReservationsByUserID" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetLockerReservationsByUserID, userID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetLockerReservationsByUserID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
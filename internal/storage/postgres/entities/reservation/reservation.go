@@ -1,6 +1,7 @@
 package reservation
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"portal/internal/storage/postgres"
@@ -37,10 +38,13 @@ type Place struct {
 	SecondScreen string `json:"second_screen,omitempty"`
 }
 
-func (p *Place) GetPlaceName(storage *postgres.Storage, placeID int) error {
+func (p *Place) GetPlaceName(ctx context.Context, db postgres.Querier, placeID int) error {
 	const op = "storage.postgres.entities.reservation.GetPlaceName"
 
-	qrResult, err := storage.DB.Query(qrGetNameByPlaceID, placeID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetNameByPlaceID, placeID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -64,10 +68,13 @@ type ActualPlace struct {
 	Finish      int  `json:"finish"`
 }
 
-func (ap *ActualPlace) GetActualPlaces(storage *postgres.Storage, properties string, start, finish time.Time) ([]ActualPlace, error) {
+func (ap *ActualPlace) GetActualPlaces(ctx context.Context, db postgres.Querier, properties string, start, finish time.Time) ([]ActualPlace, error) {
 	const op = "storage.postgres.entities.reservation.GetActualPlaces"
 
-	qrResult, err := storage.DB.Query(qrGetActualPlaces, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetActualPlaces, start, finish)
 	if err != nil {
 		if e, ok := err.(*pq.Error); ok {
 			log.Print(e.Detail)
@@ -100,10 +107,13 @@ type Reservation struct {
 	UserID        int              `json:"user_id,omitempty"`
 }
 
-func (r *Reservation) HasUserReservationInDateRange(storage *postgres.Storage, userID int, start, finish string) (bool, error) {
+func (r *Reservation) HasUserReservationInDateRange(ctx context.Context, db postgres.Querier, userID int, start, finish string) (bool, error) {
 	const op = "storage.postgres.entities.reservation.HasUserReservationInDateRange" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetUserReservationInDateRange, userID, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetUserReservationInDateRange, userID, start, finish)
 	if err != nil {
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
@@ -117,10 +127,13 @@ func (r *Reservation) HasUserReservationInDateRange(storage *postgres.Storage, u
 	return true, nil
 }
 
-func (r *Reservation) InsertReservation(storage *postgres.Storage, placeID, userID int, start, finish string) error {
+func (r *Reservation) InsertReservation(ctx context.Context, db postgres.Querier, placeID, userID int, start, finish string) error {
 	const op = "storage.postgres.entities.reservation.InsertReservation" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetIsPlaceAvailable, placeID, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetIsPlaceAvailable, placeID, start, finish)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -131,7 +144,7 @@ func (r *Reservation) InsertReservation(storage *postgres.Storage, placeID, user
 		return fmt.Errorf("%s: place is already taken", op)
 	}
 
-	_, err = storage.DB.Exec(qrInsertReservation, placeID, userID, start, finish)
+	_, err = db.ExecContext(ctx, qrInsertReservation, placeID, userID, start, finish)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -139,10 +152,13 @@ func (r *Reservation) InsertReservation(storage *postgres.Storage, placeID, user
 	return nil
 }
 
-func (r *Reservation) UpdateReservation(storage *postgres.Storage, reservationID, placeID int, start, finish time.Time) error {
+func (r *Reservation) UpdateReservation(ctx context.Context, db postgres.Querier, reservationID, placeID int, start, finish time.Time) error {
 	const op = "storage.postgres.entities.reservation.UpdateReservation" // Имя текущей функции для логов и ошибок
 
-	_, err := storage.DB.Exec(qrUpdateReservation, reservationID, placeID, start, finish)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateReservation, reservationID, placeID, start, finish)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -150,10 +166,13 @@ func (r *Reservation) UpdateReservation(storage *postgres.Storage, reservationID
 	return nil
 }
 
-func (r *Reservation) DeleteReservation(storage *postgres.Storage, reservationID int) error {
+func (r *Reservation) DeleteReservation(ctx context.Context, db postgres.Querier, reservationID int) error {
 	const op = "storage.postgres.entities.reservation.DeleteReservation" // Имя текущей функции для логов и ошибок
 
-	_, err := storage.DB.Exec(qrDeleteReservation, reservationID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteReservation, reservationID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -161,10 +180,13 @@ func (r *Reservation) DeleteReservation(storage *postgres.Storage, reservationID
 	return nil
 }
 
-func (r *Reservation) GetReservationsByUserID(storage *postgres.Storage, userID int) ([]Reservation, error) {
+func (r *Reservation) GetReservationsByUserID(ctx context.Context, db postgres.Querier, userID int) ([]Reservation, error) {
 	const op = "storage.postgres.entities.reservation.GetReservationsByUserID" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetReservationsByUserID, userID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetReservationsByUserID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
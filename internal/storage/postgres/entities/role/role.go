@@ -0,0 +1,296 @@
+package role
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/postgres"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// Роли пользователя: основная роль из "user" и дополнительные из user_role
+	qrTemplateUserRoles = `SELECT "role" FROM "user" WHERE %[1]s UNION SELECT role_id FROM user_role WHERE user_id = (SELECT user_id FROM "user" WHERE %[1]s)`
+
+	qrGetRoles                = `SELECT role_id, "name", COALESCE(ARRAY(SELECT permission FROM role_permission rp WHERE rp.role_id = r.role_id ORDER BY permission), '{}') FROM "role" r ORDER BY role_id;`
+	qrGetPermissionsByRoleID  = `SELECT permission FROM role_permission WHERE role_id = $1;`
+	qrDeleteUserRoles         = `DELETE FROM user_role WHERE user_id = $1;`
+	qrAddUserRoles            = `INSERT INTO user_role (user_id, role_id) SELECT $1, unnest($2::INT[]);`
+	qrGetAdditionalUserRoles  = `SELECT COALESCE(ARRAY(SELECT role_id FROM user_role WHERE user_id = $1 ORDER BY role_id), '{}');`
+	qrGetMainRoleForUpdate    = `SELECT "role" FROM "user" WHERE user_id = $1 FOR UPDATE;`
+	qrUpdateMainRole          = `UPDATE "user" SET "role" = $2 WHERE user_id = $1;`
+	qrDeleteUserRole          = `DELETE FROM user_role WHERE user_id = $1 AND role_id = $2;`
+	qrGetDeniedPermissions    = `SELECT COALESCE(ARRAY(SELECT permission FROM user_permission_deny WHERE user_id = $1 ORDER BY permission), '{}');`
+	qrDeleteDeniedPermissions = `DELETE FROM user_permission_deny WHERE user_id = $1;`
+	qrAddDeniedPermissions    = `INSERT INTO user_permission_deny (user_id, permission) SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING;`
+	qrNewAudit                = `INSERT INTO role_audit (user_id, target, old_roles, new_roles, changed_by, "source", creation_date) VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP);`
+	qrGetAuditByUserID        = `SELECT audit_id, user_id, target, old_roles, new_roles, changed_by, "source", creation_date FROM role_audit WHERE user_id = $1 ORDER BY creation_date DESC;`
+)
+
+// Источник изменения роли для аудита
+const (
+	SourceAdmin = "admin"
+	SourceLDAP  = "ldap"
+)
+
+// Что изменено: основная роль пользователя или дополнительные роли
+const (
+	TargetMainRole        = "role"
+	TargetAdditionalRoles = "roles"
+)
+
+var (
+	qrGetUserRoles = fmt.Sprintf(qrTemplateUserRoles, `user_id = $1`) + ` ORDER BY 1;`
+	// Права всех ролей пользователя за вычетом запрещенных ему
+	qrGetPermissionsByUsername = `SELECT permission FROM role_permission WHERE role_id IN (` + fmt.Sprintf(qrTemplateUserRoles, `lower(username) = lower($1)`) + `)
+								  EXCEPT SELECT permission FROM user_permission_deny WHERE user_id = (SELECT user_id FROM "user" WHERE lower(username) = lower($1));`
+)
+
+type Role struct {
+	RoleID      int      `json:"role_id"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+func (r *Role) GetRoles(ctx context.Context, db postgres.Querier) ([]Role, error) {
+	const op = "storage.postgres.entities.role.GetRoles"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetRoles)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	rs := []Role{}
+	for qrResult.Next() {
+		var r Role
+		if err := qrResult.Scan(&r.RoleID, &r.Name, pq.Array(&r.Permissions)); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		rs = append(rs, r)
+	}
+
+	return rs, nil
+}
+
+// GetPermissionsByUsername returns permissions of all user roles
+func (r *Role) GetPermissionsByUsername(ctx context.Context, db postgres.Querier, username string) ([]string, error) {
+	const op = "storage.postgres.entities.role.GetPermissionsByUsername"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	ps, err := getPermissions(ctx, db, qrGetPermissionsByUsername, username)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ps, nil
+}
+
+// GetPermissionsByRoleID returns permissions of the single role (e.g. scope of API client token)
+func (r *Role) GetPermissionsByRoleID(ctx context.Context, db postgres.Querier, roleID int) ([]string, error) {
+	const op = "storage.postgres.entities.role.GetPermissionsByRoleID"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	ps, err := getPermissions(ctx, db, qrGetPermissionsByRoleID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ps, nil
+}
+
+func getPermissions(ctx context.Context, db postgres.Querier, query string, arg any) ([]string, error) {
+	qrResult, err := db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer qrResult.Close()
+
+	ps := []string{}
+	for qrResult.Next() {
+		var p string
+		if err := qrResult.Scan(&p); err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+
+	return ps, nil
+}
+
+func (r *Role) GetUserRoles(ctx context.Context, db postgres.Querier, userID int) ([]int, error) {
+	const op = "storage.postgres.entities.role.GetUserRoles"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetUserRoles, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	roleIDs := []int{}
+	for qrResult.Next() {
+		var roleID int
+		if err := qrResult.Scan(&roleID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+
+	return roleIDs, nil
+}
+
+// GetDeniedPermissions returns permissions denied to the user regardless of the user roles
+func (r *Role) GetDeniedPermissions(ctx context.Context, db postgres.Querier, userID int) ([]string, error) {
+	const op = "storage.postgres.entities.role.GetDeniedPermissions"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var ps pq.StringArray
+	if err := db.QueryRowContext(ctx, qrGetDeniedPermissions, userID).Scan(&ps); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ps, nil
+}
+
+// SetDeniedPermissions replaces permissions denied to the user
+func (r *Role) SetDeniedPermissions(ctx context.Context, storage *postgres.Storage, userID int, permissions []string) error {
+	const op = "storage.postgres.entities.role.SetDeniedPermissions"
+
+	ctx, cancel := postgres.WithTxTimeout(ctx, storage)
+	defer cancel()
+
+	err := storage.WithTx(ctx, func(tx postgres.Querier) error {
+		if _, err := tx.ExecContext(ctx, qrDeleteDeniedPermissions, userID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, qrAddDeniedPermissions, userID, pq.Array(permissions))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetUserRoles replaces additional roles of the user and writes audit record. Main role from "user" table is not changed
+func (r *Role) SetUserRoles(ctx context.Context, storage *postgres.Storage, userID int, roleIDs []int, changedBy string) error {
+	const op = "storage.postgres.entities.role.SetUserRoles"
+
+	ctx, cancel := postgres.WithTxTimeout(ctx, storage)
+	defer cancel()
+
+	err := storage.WithTx(ctx, func(tx postgres.Querier) error {
+		var oldRoleIDs pq.Int64Array
+		if err := tx.QueryRowContext(ctx, qrGetAdditionalUserRoles, userID).Scan(&oldRoleIDs); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, qrDeleteUserRoles, userID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, qrAddUserRoles, userID, pq.Array(roleIDs)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, qrNewAudit, userID, TargetAdditionalRoles, oldRoleIDs, pq.Array(roleIDs), changedBy, SourceAdmin)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetMainRole changes main role of the user and writes audit record. The previous main role is also removed from
+// additional roles, so its permissions do not stay with the user. Returns false if the role is already set
+func (r *Role) SetMainRole(ctx context.Context, storage *postgres.Storage, userID, roleID int, changedBy, source string) (bool, error) {
+	const op = "storage.postgres.entities.role.SetMainRole"
+
+	ctx, cancel := postgres.WithTxTimeout(ctx, storage)
+	defer cancel()
+
+	var changed bool
+	err := storage.WithTx(ctx, func(tx postgres.Querier) error {
+		var oldRoleID int
+		if err := tx.QueryRowContext(ctx, qrGetMainRoleForUpdate, userID).Scan(&oldRoleID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storageHandler.ErrUserIDDoesNotExist
+			}
+			return err
+		}
+		if oldRoleID == roleID {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, qrUpdateMainRole, userID, roleID); err != nil {
+			return err
+		}
+		// Старая основная роль могла попасть в user_role (например, при заполнении user_role из "user"),
+		// тогда ее права остались бы у пользователя и после понижения
+		if _, err := tx.ExecContext(ctx, qrDeleteUserRole, userID, oldRoleID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, qrNewAudit, userID, TargetMainRole, pq.Array([]int{oldRoleID}), pq.Array([]int{roleID}), changedBy, source); err != nil {
+			return err
+		}
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return changed, nil
+}
+
+type Audit struct {
+	AuditID      int       `json:"audit_id"`
+	UserID       int       `json:"user_id"`
+	Target       string    `json:"target"`
+	OldRoles     []int64   `json:"old_roles"`
+	NewRoles     []int64   `json:"new_roles"`
+	ChangedBy    string    `json:"changed_by"`
+	Source       string    `json:"source"`
+	CreationDate time.Time `json:"creation_date"`
+}
+
+func (a *Audit) GetAuditByUserID(ctx context.Context, db postgres.Querier, userID int) ([]Audit, error) {
+	const op = "storage.postgres.entities.role.GetAuditByUserID"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetAuditByUserID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	as := []Audit{}
+	for qrResult.Next() {
+		var a Audit
+		if err := qrResult.Scan(&a.AuditID, &a.UserID, &a.Target, (*pq.Int64Array)(&a.OldRoles), (*pq.Int64Array)(&a.NewRoles), &a.ChangedBy, &a.Source, &a.CreationDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		as = append(as, a)
+	}
+
+	return as, nil
+}
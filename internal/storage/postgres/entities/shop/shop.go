@@ -1,6 +1,7 @@
 package shop
 
 import (
+	"context"
 	"fmt"
 	"portal/internal/storage/postgres"
 	"time"
@@ -33,10 +34,13 @@ type Item struct {
 	IsAvailable bool   `json:"is_available,omitempty"`
 }
 
-func (i *Item) DeleteItem(storage *postgres.Storage, itemID int) error {
+func (i *Item) DeleteItem(ctx context.Context, db postgres.Querier, itemID int) error {
 	const op = "storage.postgres.entities.shop.DeleteItem"
 
-	_, err := storage.DB.Exec(qrDeleteItem, itemID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteItem, itemID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -44,17 +48,20 @@ func (i *Item) DeleteItem(storage *postgres.Storage, itemID int) error {
 	return nil
 }
 
-func (i *Item) GetIsAvailable(storage *postgres.Storage, itemID int) error {
+func (i *Item) GetIsAvailable(ctx context.Context, db postgres.Querier, itemID int) error {
 	const op = "storage.postgres.entities.shop.GetIsAvailable"
 
-	qrResult, err := storage.DB.Query(qrGetIsAvailable, itemID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetIsAvailable, itemID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer qrResult.Close()
 
 	if !qrResult.Next() {
-		return fmt.Errorf("%s: %w", op, "item is not exist")
+		return fmt.Errorf("%s: %w", op, storageHandler.ErrItemDoesNotExist)
 	}
 
 	if err := qrResult.Scan(&i.IsAvailable); err != nil {
@@ -64,10 +71,13 @@ func (i *Item) GetIsAvailable(storage *postgres.Storage, itemID int) error {
 	return nil
 }
 
-func (i *Item) GetItems(storage *postgres.Storage) ([]Item, error) {
+func (i *Item) GetItems(ctx context.Context, db postgres.Querier) ([]Item, error) {
 	const op = "storage.postgres.entities.shop.GetItems"
 
-	qrResult, err := storage.DB.Query(qrGetItems)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetItems)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -92,10 +102,13 @@ type InCartItem struct {
 	Quantity     int `json:"quantity,omitempty"`
 }
 
-func (ici *InCartItem) NewInCartItem(storage *postgres.Storage, itemID, quantity, cartID int) error {
+func (ici *InCartItem) NewInCartItem(ctx context.Context, db postgres.Querier, itemID, quantity, cartID int) error {
 	const op = "storage.postgres.entities.shop.NewInCartItem"
 
-	_, err := storage.DB.Exec(qrNewInCartItem, itemID, quantity, cartID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrNewInCartItem, itemID, quantity, cartID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -103,10 +116,13 @@ func (ici *InCartItem) NewInCartItem(storage *postgres.Storage, itemID, quantity
 	return nil
 }
 
-func (ici *InCartItem) DeleteInCartItem(storage *postgres.Storage, inCartItemID int) error {
+func (ici *InCartItem) DeleteInCartItem(ctx context.Context, db postgres.Querier, inCartItemID int) error {
 	const op = "storage.postgres.entities.shop.DeleteInCartItem"
 
-	_, err := storage.DB.Exec(qrDeleteInCartItem, inCartItemID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteInCartItem, inCartItemID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -114,10 +130,13 @@ func (ici *InCartItem) DeleteInCartItem(storage *postgres.Storage, inCartItemID
 	return nil
 }
 
-func (ici *InCartItem) UpdateInCartItem(storage *postgres.Storage, inCartItemID, quantity int) error {
+func (ici *InCartItem) UpdateInCartItem(ctx context.Context, db postgres.Querier, inCartItemID, quantity int) error {
 	const op = "storage.postgres.entities.shop.UpdateInCartItem"
 
-	_, err := storage.DB.Exec(qrUpdateInCartItem, quantity, inCartItemID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateInCartItem, quantity, inCartItemID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -125,10 +144,13 @@ func (ici *InCartItem) UpdateInCartItem(storage *postgres.Storage, inCartItemID,
 	return nil
 }
 
-func (ici *InCartItem) GetInCartItems(storage *postgres.Storage, cartID int) ([]InCartItem, error) {
+func (ici *InCartItem) GetInCartItems(ctx context.Context, db postgres.Querier, cartID int) ([]InCartItem, error) {
 	const op = "storage.postgres.entities.shop.GetInCartItems"
 
-	qrResult, err := storage.DB.Query(qrGetInCartItems, cartID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetInCartItems, cartID)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -154,10 +176,13 @@ type Cart struct {
 	Date     time.Time `json:"date,omitempty"`
 }
 
-func (c *Cart) UpdateCartToInactive(storage *postgres.Storage, userID int) error {
+func (c *Cart) UpdateCartToInactive(ctx context.Context, db postgres.Querier, userID int) error {
 	const op = "storage.postgres.entities.shop.UpdateCartToInactive"
 
-	_, err := storage.DB.Exec(qrUpdateCartToInactive, userID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrUpdateCartToInactive, userID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -165,10 +190,13 @@ func (c *Cart) UpdateCartToInactive(storage *postgres.Storage, userID int) error
 	return nil
 }
 
-func (c *Cart) GetActiveCartID(storage *postgres.Storage, userID int) error {
+func (c *Cart) GetActiveCartID(ctx context.Context, db postgres.Querier, userID int) error {
 	const op = "storage.postgres.entities.shop.GetActiveCartID"
 
-	qrResult, err := storage.DB.Query(qrGetActiveCartID, userID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetActiveCartID, userID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -185,10 +213,13 @@ func (c *Cart) GetActiveCartID(storage *postgres.Storage, userID int) error {
 	return nil
 }
 
-func (c *Cart) NewCart(storage *postgres.Storage, userID int) error {
+func (c *Cart) NewCart(ctx context.Context, db postgres.Querier, userID int) error {
 	const op = "storage.postgres.entities.shop.NewCart"
 
-	_, err := storage.DB.Exec(qrNewCart, userID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrNewCart, userID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -196,10 +227,13 @@ func (c *Cart) NewCart(storage *postgres.Storage, userID int) error {
 	return nil
 }
 
-func (c *Cart) EmptyCart(storage *postgres.Storage, userID int) error {
+func (c *Cart) EmptyCart(ctx context.Context, db postgres.Querier, userID int) error {
 	const op = "storage.postgres.entities.shop.EmptyCart"
 
-	_, err := storage.DB.Exec(qrDeleteInCartItemsByCartID, userID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteInCartItemsByCartID, userID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
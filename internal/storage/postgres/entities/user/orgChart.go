@@ -0,0 +1,172 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"portal/internal/storage/postgres"
+
+	"github.com/lib/pq"
+)
+
+const (
+	qrGetOrgChart = `SELECT user_id, full_name, position, department, COALESCE(image_path, ''), COALESCE(chief_id, 0)
+					 FROM "user" WHERE is_active AND ($1 = '' OR department = $1) ORDER BY full_name;`
+	qrGetDirectReports = `SELECT user_id, full_name, position, department, COALESCE(image_path, ''), COALESCE(chief_id, 0)
+						  FROM "user" WHERE is_active AND chief_id = $1 ORDER BY full_name;`
+	// Цепочка руководителей от непосредственного до высшего. Глубина ограничена на случай циклов в каталоге
+	qrGetChainOfCommand = `WITH RECURSIVE chain AS (
+							   SELECT chief_id, 1 AS depth FROM "user" WHERE user_id = $1
+							   UNION ALL
+							   SELECT u.chief_id, chain.depth + 1 FROM "user" u JOIN chain ON u.user_id = chain.chief_id WHERE chain.depth < 50
+						   )
+						   SELECT u.user_id, u.full_name, u.position, u.department, COALESCE(u.image_path, ''), COALESCE(u.chief_id, 0)
+						   FROM chain JOIN "user" u ON u.user_id = chain.chief_id ORDER BY chain.depth;`
+	// Руководитель задается по username, пустой username сбрасывает руководителя. Возвращает количество измененных пользователей
+	qrSetChiefs = `UPDATE "user" u SET chief_id = c.user_id
+				   FROM unnest($1::TEXT[], $2::TEXT[]) AS m(username, chief_username)
+				   LEFT JOIN "user" c ON lower(c.username) = lower(m.chief_username)
+				   WHERE lower(u.username) = lower(m.username) AND u.chief_id IS DISTINCT FROM c.user_id;`
+)
+
+// OrgChartNode is the user in the organisation chart
+type OrgChartNode struct {
+	UserID     int             `json:"user_id"`
+	FullName   string          `json:"full_name"`
+	Position   string          `json:"position"`
+	Department string          `json:"department"`
+	ImagePath  string          `json:"image_path"`
+	ChiefID    int             `json:"chief_id,omitempty"`
+	Reports    []*OrgChartNode `json:"reports,omitempty"`
+}
+
+// GetOrgChart returns trees of subordination. With department only its users are included,
+// roots are users whose chief is not in the department
+func (n *OrgChartNode) GetOrgChart(ctx context.Context, db postgres.Querier, department string) ([]*OrgChartNode, error) {
+	const op = "storage.postgres.entities.user.GetOrgChart"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	nodes, err := getOrgChartNodes(ctx, db, qrGetOrgChart, department)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return buildOrgChart(nodes), nil
+}
+
+// buildOrgChart links nodes to their chiefs and returns roots: nodes whose chief is not in nodes.
+// Chief cycles without such root are not dropped, one user of the cycle becomes an extra root
+func buildOrgChart(nodes []OrgChartNode) []*OrgChartNode {
+	byID := make(map[int]*OrgChartNode, len(nodes))
+	reports := make(map[int][]*OrgChartNode, len(nodes))
+	for i := range nodes {
+		byID[nodes[i].UserID] = &nodes[i]
+		reports[nodes[i].ChiefID] = append(reports[nodes[i].ChiefID], &nodes[i])
+	}
+
+	// Дерево строится обходом от корней, поэтому циклы в каталоге (A руководит B, B руководит A) не приводят к зацикливанию
+	visited := make(map[int]bool, len(nodes))
+	var attach func(node *OrgChartNode)
+	attach = func(node *OrgChartNode) {
+		visited[node.UserID] = true
+		for _, report := range reports[node.UserID] {
+			if !visited[report.UserID] {
+				node.Reports = append(node.Reports, report)
+				attach(report)
+			}
+		}
+	}
+
+	roots := []*OrgChartNode{}
+	for i := range nodes {
+		if _, ok := byID[nodes[i].ChiefID]; !ok {
+			roots = append(roots, &nodes[i])
+			attach(&nodes[i])
+		}
+	}
+	// Остались только пользователи из циклов и их подчиненные: корнем становится руководитель из самого цикла,
+	// до него поднимаемся по цепочке руководителей, чтобы подчиненные не оказались отдельными корнями
+	for i := range nodes {
+		if visited[nodes[i].UserID] {
+			continue
+		}
+		root := &nodes[i]
+		seen := map[int]bool{}
+		for !seen[root.UserID] {
+			seen[root.UserID] = true
+			root = byID[root.ChiefID]
+		}
+		roots = append(roots, root)
+		attach(root)
+	}
+
+	return roots
+}
+
+func (n *OrgChartNode) GetDirectReports(ctx context.Context, db postgres.Querier, userID int) ([]OrgChartNode, error) {
+	const op = "storage.postgres.entities.user.GetDirectReports"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	nodes, err := getOrgChartNodes(ctx, db, qrGetDirectReports, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nodes, nil
+}
+
+// GetChainOfCommand returns chiefs of the user from the direct chief to the top
+func (n *OrgChartNode) GetChainOfCommand(ctx context.Context, db postgres.Querier, userID int) ([]OrgChartNode, error) {
+	const op = "storage.postgres.entities.user.GetChainOfCommand"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	nodes, err := getOrgChartNodes(ctx, db, qrGetChainOfCommand, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nodes, nil
+}
+
+func getOrgChartNodes(ctx context.Context, db postgres.Querier, query string, arg any) ([]OrgChartNode, error) {
+	qrResult, err := db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer qrResult.Close()
+
+	nodes := []OrgChartNode{}
+	for qrResult.Next() {
+		var n OrgChartNode
+		if err := qrResult.Scan(&n.UserID, &n.FullName, &n.Position, &n.Department, &n.ImagePath, &n.ChiefID); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil
+}
+
+// SetChiefs links users to their chiefs by usernames and returns the number of changed users
+func (u *User) SetChiefs(ctx context.Context, db postgres.Querier, usernames, chiefUsernames []string) (int, error) {
+	const op = "storage.postgres.entities.user.SetChiefs"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, qrSetChiefs, pq.Array(usernames), pq.Array(chiefUsernames))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int(updated), nil
+}
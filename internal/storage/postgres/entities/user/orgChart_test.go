@@ -0,0 +1,74 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// orgChartString prints trees as "1(2(3),4)" to compare them in tests
+func orgChartString(nodes []*OrgChartNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		part := fmt.Sprint(n.UserID)
+		if len(n.Reports) != 0 {
+			part += "(" + orgChartString(n.Reports) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestBuildOrgChart(t *testing.T) {
+	tests := []struct {
+		name string
+		// Пары user_id, chief_id в порядке выдачи запросом
+		users [][2]int
+		want  string
+	}{
+		{name: "empty", users: nil, want: ""},
+		{name: "tree", users: [][2]int{{1, 0}, {2, 1}, {3, 2}, {4, 1}}, want: "1(2(3),4)"},
+		{name: "chief out of department", users: [][2]int{{2, 1}, {3, 2}, {4, 9}}, want: "2(3),4"},
+		{name: "cycle without root", users: [][2]int{{1, 2}, {2, 1}}, want: "1(2)"},
+		{name: "self chief", users: [][2]int{{1, 1}}, want: "1"},
+		{name: "cycle next to tree", users: [][2]int{{1, 0}, {2, 1}, {3, 4}, {4, 3}}, want: "1(2),3(4)"},
+		// Подчиненный идет в выдаче раньше руководителей из цикла, но корнем не становится
+		{name: "report of cycle first", users: [][2]int{{5, 3}, {3, 4}, {4, 3}}, want: "3(5,4)"},
+		{name: "long cycle with reports", users: [][2]int{{6, 1}, {1, 3}, {2, 1}, {3, 2}, {7, 2}}, want: "1(6,2(3,7))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := make([]OrgChartNode, 0, len(tt.users))
+			for _, u := range tt.users {
+				nodes = append(nodes, OrgChartNode{UserID: u[0], ChiefID: u[1]})
+			}
+
+			roots := buildOrgChart(nodes)
+			if got := orgChartString(roots); got != tt.want {
+				t.Errorf("buildOrgChart() = %q, want %q", got, tt.want)
+			}
+
+			// Каждый пользователь попадает в дерево ровно один раз
+			var ids []int
+			var walk func(ns []*OrgChartNode)
+			walk = func(ns []*OrgChartNode) {
+				for _, n := range ns {
+					ids = append(ids, n.UserID)
+					walk(n.Reports)
+				}
+			}
+			walk(roots)
+			sort.Ints(ids)
+			for i := 1; i < len(ids); i++ {
+				if ids[i] == ids[i-1] {
+					t.Errorf("user %d is in the chart twice", ids[i])
+				}
+			}
+			if len(ids) != len(nodes) {
+				t.Errorf("chart has %d users, want %d", len(ids), len(nodes))
+			}
+		})
+	}
+}
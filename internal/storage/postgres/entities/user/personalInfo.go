@@ -0,0 +1,133 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/postgres"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Поля из каталога, которые может исправить администратор. Значения совпадают с именами колонок "user"
+const (
+	FieldFullName   = "full_name"
+	FieldPosition   = "position"
+	FieldDepartment = "department"
+	FieldMobile     = "mobile"
+	FieldMail       = "mail"
+)
+
+var OverridableFields = []string{FieldFullName, FieldPosition, FieldDepartment, FieldMobile, FieldMail}
+
+const (
+	qrGetPersonalInfo = `SELECT COALESCE(extra_phone, ''), COALESCE(office, ''), COALESCE(bio, ''), COALESCE(to_char(birthday, 'YYYY-MM-DD'), ''),
+						 show_birthday, skills, overridden_fields
+						 FROM "user" WHERE user_id = $1;`
+	qrUpdatePersonalInfo = `UPDATE "user" SET extra_phone = NULLIF($2, ''), office = NULLIF($3, ''), bio = NULLIF($4, ''),
+							birthday = NULLIF($5, '')::date, show_birthday = $6, skills = $7
+							WHERE user_id = $1;`
+	// %s - присваивания исправленных полей. $2 - исправленные поля, $3 - поля, которые снова берутся из каталога
+	qrTemplateOverrideFields = `UPDATE "user" SET %s
+								overridden_fields = ARRAY(SELECT DISTINCT f FROM unnest(overridden_fields || $2::text[]) f WHERE NOT (f = ANY($3::text[])) ORDER BY f)
+								WHERE user_id = $1;`
+)
+
+// syncValue returns the value of the column for directory sync which keeps the value overridden by administrator
+func syncValue(column string) string {
+	return fmt.Sprintf(`CASE WHEN '%[1]s' = ANY("user".overridden_fields) THEN "user".%[1]s ELSE EXCLUDED.%[1]s END`, column)
+}
+
+// GetPersonalInfo gets fields filled on the portal and overridden fields list
+func (u *User) GetPersonalInfo(ctx context.Context, db postgres.Querier, userID int) error {
+	const op = "storage.postgres.entities.user.GetPersonalInfo"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var skills, overriddenFields pq.StringArray
+	err := db.QueryRowContext(ctx, qrGetPersonalInfo, userID).Scan(&u.ExtraPhone, &u.Office, &u.Bio, &u.Birthday, &u.ShowBirthday, &skills, &overriddenFields)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storageHandler.ErrUserIDDoesNotExist)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	u.Skills = []string(skills)
+	u.OverriddenFields = []string(overriddenFields)
+
+	return nil
+}
+
+// UpdatePersonalInfo saves fields filled on the portal from u
+func (u *User) UpdatePersonalInfo(ctx context.Context, db postgres.Querier, userID int) error {
+	const op = "storage.postgres.entities.user.UpdatePersonalInfo"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	skills := u.Skills
+	if skills == nil {
+		skills = []string{}
+	}
+
+	res, err := db.ExecContext(ctx, qrUpdatePersonalInfo, userID, u.ExtraPhone, u.Office, u.Bio, u.Birthday, u.ShowBirthday, pq.Array(skills))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, storageHandler.ErrUserIDDoesNotExist)
+	}
+
+	return nil
+}
+
+// OverrideFields sets directory fields of the user by administrator. Overridden fields are kept across directory syncs.
+// Reset fields are taken from the directory again on the next sync
+func (u *User) OverrideFields(ctx context.Context, db postgres.Querier, userID int, values map[string]string, reset []string) error {
+	const op = "storage.postgres.entities.user.OverrideFields"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	args := []any{userID, nil, pq.Array(reset)}
+	fields := make([]string, 0, len(values))
+	var set strings.Builder
+	// Порядок полей фиксирован, имена колонок берутся только из OverridableFields
+	for _, field := range OverridableFields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		if slices.Contains(reset, field) {
+			return fmt.Errorf("%s: field %s is both overridden and reset", op, field)
+		}
+		args = append(args, value)
+		fields = append(fields, field)
+		set.WriteString(field + " = $" + strconv.Itoa(len(args)) + ", ")
+	}
+	if len(fields) != len(values) {
+		return fmt.Errorf("%s: field is not overridable", op)
+	}
+	for _, field := range reset {
+		if !slices.Contains(OverridableFields, field) {
+			return fmt.Errorf("%s: field %s is not overridable", op, field)
+		}
+	}
+	args[1] = pq.Array(fields)
+
+	res, err := db.ExecContext(ctx, fmt.Sprintf(qrTemplateOverrideFields, set.String()), args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, storageHandler.ErrUserIDDoesNotExist)
+	}
+
+	return nil
+}
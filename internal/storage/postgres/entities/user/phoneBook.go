@@ -0,0 +1,158 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	storageHandler "portal/internal/storage"
+	"portal/internal/storage/postgres"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Выражение для поиска по справочнику. Совпадает с выражением триграммного индекса user_search_trgm_idx,
+// номер телефона дополнительно индексируется только цифрами
+const phoneBookSearchExpr = `lower(COALESCE(full_name, '') || ' ' || COALESCE(position, '') || ' ' || COALESCE(department, '') || ' ' ||
+	COALESCE(mobile, '') || ' ' || regexp_replace(COALESCE(mobile, ''), '\D', '', 'g'))`
+
+// %[1]s - поле сортировки, %[2]s - оператор сравнения для курсора, %[3]s - направление сортировки
+const qrTemplateGetPhoneBookPage = `SELECT user_id, COALESCE(full_name, ''), COALESCE(position, ''), COALESCE(department, ''), COALESCE(mail, ''),
+	COALESCE(mobile, '-'), COALESCE(image_path, ''), COALESCE(%[1]s, '')
+	FROM "user"
+	WHERE is_active AND ` + phoneBookSearchExpr + ` LIKE ALL($1) AND ($2 = '' OR department = $2)
+	AND ($3 OR (COALESCE(%[1]s, ''), user_id) %[2]s ($4, $5))
+	ORDER BY COALESCE(%[1]s, '') %[3]s, user_id %[3]s
+	LIMIT $6;`
+
+const (
+	PhoneBookDefaultLimit = 50
+	PhoneBookMaxLimit     = 200
+)
+
+// Поля, доступные для сортировки справочника
+var phoneBookSortFields = map[string]string{
+	"full_name":  "full_name",
+	"position":   "position",
+	"department": "department",
+}
+
+// PhoneBookQuery defines search, filter, sorting and page of the phone book
+type PhoneBookQuery struct {
+	Search     string
+	Department string
+	SortBy     string // full_name, position или department
+	Desc       bool
+	Cursor     string // Пустой курсор - первая страница
+	Limit      int
+}
+
+// phoneBookCursor is the key of the last entry of the page
+type phoneBookCursor struct {
+	SortKey string `json:"k"`
+	UserID  int    `json:"id"`
+}
+
+// encode returns opaque cursor passed to the client
+func (c phoneBookCursor) encode() (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodePhoneBookCursor parses cursor made by encode. Any malformed cursor is ErrInvalidCursor
+func decodePhoneBookCursor(cursor string) (phoneBookCursor, error) {
+	var c phoneBookCursor
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return phoneBookCursor{}, storageHandler.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return phoneBookCursor{}, storageHandler.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// likePatterns makes pattern for each word of the search, so all words must be found in any order
+func likePatterns(search string) []string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	words := strings.Fields(strings.ToLower(search))
+	patterns := make([]string, 0, len(words))
+	for _, word := range words {
+		patterns = append(patterns, "%"+replacer.Replace(word)+"%")
+	}
+	return patterns
+}
+
+// GetPhoneBookPage returns page of active users and cursor of the next page. Empty cursor means the last page
+func (u *User) GetPhoneBookPage(ctx context.Context, db postgres.Querier, q PhoneBookQuery) ([]User, string, error) {
+	const op = "storage.postgres.entities.user.GetPhoneBookPage"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	if q.SortBy == "" {
+		q.SortBy = "full_name"
+	}
+	sortField, ok := phoneBookSortFields[q.SortBy]
+	if !ok {
+		return nil, "", fmt.Errorf("%s: %w", op, storageHandler.ErrInvalidSortField)
+	}
+	if q.Limit <= 0 {
+		q.Limit = PhoneBookDefaultLimit
+	}
+	if q.Limit > PhoneBookMaxLimit {
+		q.Limit = PhoneBookMaxLimit
+	}
+
+	var cursor phoneBookCursor
+	if q.Cursor != "" {
+		var err error
+		if cursor, err = decodePhoneBookCursor(q.Cursor); err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	comparison, direction := ">", "ASC"
+	if q.Desc {
+		comparison, direction = "<", "DESC"
+	}
+	query := fmt.Sprintf(qrTemplateGetPhoneBookPage, sortField, comparison, direction)
+
+	// Запрашиваем на одну запись больше, чтобы определить наличие следующей страницы
+	qrResult, err := db.QueryContext(ctx, query, pq.Array(likePatterns(q.Search)), q.Department, q.Cursor == "", cursor.SortKey, cursor.UserID, q.Limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	us := []User{}
+	var sortKeys []string
+	for qrResult.Next() {
+		var u User
+		var sortKey string
+		if err := qrResult.Scan(&u.UserID, &u.FullName, &u.Position, &u.Department, &u.Mail, &u.Mobile, &u.ImagePath, &sortKey); err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+		us = append(us, u)
+		sortKeys = append(sortKeys, sortKey)
+	}
+	if err := qrResult.Err(); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(us) <= q.Limit {
+		return us, "", nil
+	}
+
+	us = us[:q.Limit]
+	next, err := phoneBookCursor{SortKey: sortKeys[q.Limit-1], UserID: us[q.Limit-1].UserID}.encode()
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return us, next, nil
+}
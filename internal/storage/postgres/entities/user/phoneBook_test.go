@@ -0,0 +1,88 @@
+package user
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	storageHandler "portal/internal/storage"
+)
+
+func TestPhoneBookCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor phoneBookCursor
+	}{
+		{name: "simple", cursor: phoneBookCursor{SortKey: "Иванов Иван", UserID: 42}},
+		{name: "empty sort key", cursor: phoneBookCursor{SortKey: "", UserID: 1}},
+		{name: "special characters", cursor: phoneBookCursor{SortKey: `"quoted" \ / +=`, UserID: 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.cursor.encode()
+			if err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+
+			// Курсор передается в query параметре, поэтому должен быть URL-безопасным
+			if _, err := base64.RawURLEncoding.DecodeString(encoded); err != nil {
+				t.Errorf("encode() = %q is not raw url base64", encoded)
+			}
+
+			decoded, err := decodePhoneBookCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodePhoneBookCursor(%q) error = %v", encoded, err)
+			}
+			if decoded != tt.cursor {
+				t.Errorf("decodePhoneBookCursor(%q) = %+v, want %+v", encoded, decoded, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodePhoneBookCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!!!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"k":"a","id":1}`))},
+		{name: "not json", cursor: base64.RawURLEncoding.EncodeToString([]byte("abc"))},
+		{name: "wrong types", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"k":1,"id":"a"}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodePhoneBookCursor(tt.cursor)
+			if !errors.Is(err, storageHandler.ErrInvalidCursor) {
+				t.Errorf("decodePhoneBookCursor(%q) error = %v, want %v", tt.cursor, err, storageHandler.ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestLikePatterns(t *testing.T) {
+	tests := []struct {
+		name   string
+		search string
+		want   []string
+	}{
+		{name: "empty", search: "", want: []string{}},
+		{name: "spaces only", search: "   ", want: []string{}},
+		{name: "one word", search: "Иванов", want: []string{"%иванов%"}},
+		{name: "several words", search: "  Иван   ОТДЕЛ ", want: []string{"%иван%", "%отдел%"}},
+		{name: "phone", search: "+7 999", want: []string{"%+7%", "%999%"}},
+		{name: "like wildcards", search: "100% a_b", want: []string{`%100\%%`, `%a\_b%`}},
+		{name: "backslash", search: `a\b`, want: []string{`%a\\b%`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := likePatterns(tt.search); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("likePatterns(%q) = %q, want %q", tt.search, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,80 @@
 package user
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	storageHandler "portal/internal/storage"
 	"portal/internal/storage/mssql"
 	"portal/internal/storage/postgres"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	qrNewUser              = `INSERT INTO "user" (role, balance, username, full_name, position, department, mobile, mail) VALUES ($7, 0, $1, $2, $3, $4, $5, $6) RETURNING user_id;`
+	qrGetUserFullName      = `SELECT _Fld7254 FROM [10295].[dbo].[_InfoRg7251] WHERE _Fld7252 = $1;`
+	qrGetUserInfo          = `SELECT full_name, position, department, COALESCE(mail, ''), COALESCE(mobile, '-'), COALESCE(chief, '-'), COALESCE(chief_id, 0) FROM "user" WHERE lower(username) = lower($1);`
+	qrGetAllUsersInfo      = `SELECT full_name, position, department, COALESCE(mail, ''), COALESCE(mobile, '-') FROM "user" WHERE is_active;`
+	qrGetRole              = `SELECT "role" FROM "user" WHERE lower(username) = lower($1);`
+	qrGetPassByUsername    = `SELECT "password" FROM "user" WHERE lower(username) = lower($1);`
+	qrGetUserIDByUsername  = `SELECT user_id FROM "user" WHERE lower(username) = lower($1);`
+	qrGetUserById          = `SELECT "1c" FROM "user" WHERE user_id = $1;`
+	qrGetUsernameByUserID  = `SELECT username FROM "user" WHERE user_id = $1;`
+	qrGetImagePathByUserID = `SELECT COALESCE(image_path, '') FROM "user" WHERE user_id = $1;`
+	// Блокировка в подзапросе возвращает путь, который действительно заменен, даже при параллельной загрузке
+	qrReplaceImagePath = `UPDATE "user" u SET image_path = NULLIF($2, '')
+						  FROM (SELECT user_id, image_path FROM "user" WHERE user_id = $1 FOR UPDATE) old
+						  WHERE u.user_id = old.user_id
+						  RETURNING COALESCE(old.image_path, '');`
+	// Сессия уникальна для пары пользователь/устройство: повторный вход с того же устройства заменяет сессию
+	qrNewSession = `INSERT INTO "session" (session_id, user_id, device_id, token_id, refresh_token_id, user_agent, ip, creation_date, last_used_date)
+					VALUES ($1, (SELECT user_id FROM "user" WHERE lower(username) = lower($2)), $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+					ON CONFLICT (user_id, device_id) DO UPDATE
+					SET session_id = EXCLUDED.session_id, token_id = EXCLUDED.token_id, refresh_token_id = EXCLUDED.refresh_token_id,
+					prev_refresh_token_id = NULL, rotation_date = NULL, user_agent = EXCLUDED.user_agent,
+					ip = EXCLUDED.ip, creation_date = EXCLUDED.creation_date, last_used_date = EXCLUDED.last_used_date;`
+	qrRotateRefreshTokenID = `UPDATE "session" SET token_id = $4, refresh_token_id = $5, prev_refresh_token_id = refresh_token_id,
+							  rotation_date = CURRENT_TIMESTAMP, last_used_date = CURRENT_TIMESTAMP
+							  WHERE session_id = $1 AND refresh_token_id = $3 AND user_id = (SELECT user_id FROM "user" WHERE lower(username) = lower($2));`
+	// Access токен сессии, если предыдущий refresh токен заменен не раньше $4 секунд назад
+	qrGetRotatedTokenID = `SELECT token_id FROM "session"
+						   WHERE session_id = $1 AND prev_refresh_token_id = $3 AND user_id = (SELECT user_id FROM "user" WHERE lower(username) = lower($2))
+						   AND rotation_date > CURRENT_TIMESTAMP - $4 * interval '1 second';`
+	qrGetSessionsByUserID = `SELECT session_id, user_agent, COALESCE(ip, ''), creation_date, last_used_date FROM "session" WHERE user_id = $1 ORDER BY last_used_date DESC;`
+	// Удаление сессий с добавлением их текущих access токенов в denylist. Возвращает количество удаленных сессий
+	qrTemplateRevokeSessions = `WITH s AS (DELETE FROM "session" WHERE %s RETURNING token_id),
+								t AS (INSERT INTO revoked_token (token_id, revocation_date)
+									  SELECT token_id, CURRENT_TIMESTAMP FROM s WHERE token_id IS NOT NULL ON CONFLICT DO NOTHING)
+								SELECT count(*) FROM s;`
+	qrIsTokenRevoked         = `SELECT EXISTS(SELECT 1 FROM revoked_token WHERE token_id = $1);`
+	qrDeleteOldRevokedTokens = `DELETE FROM revoked_token WHERE revocation_date < $1;`
 )
 
 const (
-	qrNewUser                   = `INSERT INTO "user" (role, balance, username, full_name, position, department, mobile, mail) VALUES ($7, 0, $1, $2, $3, $4, $5, $6) RETURNING user_id;`
-	qrGetUserFullName           = `SELECT _Fld7254 FROM [10295].[dbo].[_InfoRg7251] WHERE _Fld7252 = $1;`
-	qrGetUserInfo               = `SELECT full_name, position, department, COALESCE(mail, ''), COALESCE(mobile, '-'), COALESCE(chief, '-') FROM "user" WHERE username = $1;`
-	qrGetAllUsersInfo           = `SELECT full_name, position, department, COALESCE(mail, ''), COALESCE(mobile, '-') FROM "user";`
-	qrGetRole                   = `SELECT "role" FROM "user" WHERE username = $1;`
-	qrGetPassByUsername         = `SELECT "password" FROM "user" WHERE username = $1;`
-	qrGetUserIDByUsername       = `SELECT user_id FROM "user" WHERE username = $1;`
-	qrGetUserById               = `SELECT "1c" FROM "user" WHERE user_id = $1;`
-	qrGetUsernameByUserID       = `SELECT username FROM "user" WHERE user_id = $1;`
-	qrGetImagePathByUserID      = `SELECT COALESCE(image_path, '') FROM "user" WHERE user_id = $1;`
-	qrGetRefreshTokenIDByUserID = `SELECT refresh_token_id FROM refresh_token WHERE user_id = $1;`
-	qrStoreRefreshTokenID       = `INSERT INTO refresh_token (user_id, refresh_token_id)
-								   VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE
-								   SET refresh_token_id = EXCLUDED.refresh_token_id;`
+	// Синхронизация с каталогом: строка без изменений не обновляется и не возвращается.
+	// %[1]s..%[5]s - значения полей с учетом исправлений администратора, см. syncValue
+	qrTemplateSyncUser = `WITH old AS (SELECT is_active FROM "user" WHERE lower(username) = lower($2))
+				  INSERT INTO "user" (role, balance, username, full_name, position, department, mobile, mail, chief, is_active)
+				  VALUES ($1, 0, $2, $3, $4, $5, $6, $7, $8, $9)
+				  ON CONFLICT ((lower(username))) DO UPDATE
+				  SET full_name = %[1]s, position = %[2]s, department = %[3]s,
+				  mobile = %[4]s, mail = %[5]s, chief = EXCLUDED.chief, is_active = EXCLUDED.is_active
+				  WHERE ("user".full_name, "user".position, "user".department, "user".mobile, "user".mail, "user".chief, "user".is_active)
+				  IS DISTINCT FROM (%[1]s, %[2]s, %[3]s, %[4]s, %[5]s, EXCLUDED.chief, EXCLUDED.is_active)
+				  RETURNING (SELECT is_active FROM old);`
+	qrDeactivateMissingUsers = `UPDATE "user" SET is_active = FALSE WHERE is_active AND NOT (lower(username) = ANY(SELECT lower(u) FROM unnest($1::text[]) AS u)) RETURNING username;`
+)
+
+var (
+	qrRevokeSession          = fmt.Sprintf(qrTemplateRevokeSessions, `session_id = $1`)
+	qrRevokeUserSession      = fmt.Sprintf(qrTemplateRevokeSessions, `session_id = $1 AND user_id = $2`)
+	qrRevokeUsernameSession  = fmt.Sprintf(qrTemplateRevokeSessions, `session_id = $1 AND user_id = (SELECT user_id FROM "user" WHERE lower(username) = lower($2))`)
+	qrRevokeSessionsByUserID = fmt.Sprintf(qrTemplateRevokeSessions, `user_id = $1`)
+
+	qrSyncUser = fmt.Sprintf(qrTemplateSyncUser, syncValue(FieldFullName), syncValue(FieldPosition), syncValue(FieldDepartment), syncValue(FieldMobile), syncValue(FieldMail))
 )
 
 type User struct {
@@ -37,13 +90,88 @@ type User struct {
 	Mail       string `json:"mail"`
 	Mobile     string `json:"mobile"`
 	Chief      string `json:"chief"`
+	ChiefID    int    `json:"chief_id,omitempty"`
 	ImagePath  string `json:"image_path"`
+	IsActive   bool   `json:"-"`
+
+	// Поля, которые пользователь заполняет на портале
+	ExtraPhone   string   `json:"extra_phone"`
+	Office       string   `json:"office"`
+	Bio          string   `json:"bio"`
+	Birthday     string   `json:"birthday,omitempty"` // Формат 2006-01-02
+	ShowBirthday bool     `json:"show_birthday"`
+	Skills       []string `json:"skills"`
+
+	// Поля из каталога, исправленные администратором. Синхронизация их не перезаписывает
+	OverriddenFields []string `json:"overridden_fields"`
 }
 
-func (u *User) NewUser(storage *postgres.Storage, username, fullName, position, department, mobile, mail string, role int) error {
+// Результат синхронизации пользователя с каталогом
+const (
+	SyncUnchanged   = "unchanged"
+	SyncCreated     = "created"
+	SyncUpdated     = "updated"
+	SyncDeactivated = "deactivated"
+)
+
+// SyncUser creates or updates user by directory data from u fields. Role is used only for new users
+func (u *User) SyncUser(ctx context.Context, db postgres.Querier, role int) (string, error) {
+	const op = "storage.postgres.entities.user.SyncUser"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var wasActive sql.NullBool
+	err := db.QueryRowContext(ctx, qrSyncUser, role, u.Username, u.FullName, u.Position, u.Department, u.Mobile, u.Mail, u.Chief, u.IsActive).Scan(&wasActive)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SyncUnchanged, nil
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case !wasActive.Valid:
+		return SyncCreated, nil
+	case wasActive.Bool && !u.IsActive:
+		return SyncDeactivated, nil
+	default:
+		return SyncUpdated, nil
+	}
+}
+
+// DeactivateMissingUsers deactivates users which are not in the directory anymore and returns their usernames
+func (u *User) DeactivateMissingUsers(ctx context.Context, db postgres.Querier, directoryUsernames []string) ([]string, error) {
+	const op = "storage.postgres.entities.user.DeactivateMissingUsers"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrDeactivateMissingUsers, pq.Array(directoryUsernames))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	usernames := []string{}
+	for qrResult.Next() {
+		var username string
+		if err := qrResult.Scan(&username); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		usernames = append(usernames, username)
+	}
+
+	return usernames, nil
+}
+
+func (u *User) NewUser(ctx context.Context, db postgres.Querier, username, fullName, position, department, mobile, mail string, role int) error {
 	const op = "storage.postgres.entities.user.NewUser"
 
-	err := storage.DB.QueryRow(qrNewUser, username, fullName, position, department, mobile, mail, role).Scan(&u.UserID)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, qrNewUser, username, fullName, position, department, mobile, mail, role).Scan(&u.UserID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -51,10 +179,10 @@ func (u *User) NewUser(storage *postgres.Storage, username, fullName, position,
 	return nil
 }
 
-/*func (u *User) GetUserById(storage *postgres.Storage) error {
+/*func (u *User) GetUserById(ctx context.Context, db postgres.Querier) error {
 	const op = "storage.postgres.entities.user.GetUserById" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetUserById, u.UserID)
+	qrResult, err := db.QueryContext(ctx, qrGetUserById, u.UserID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -72,17 +200,20 @@ func (u *User) NewUser(storage *postgres.Storage, username, fullName, position,
 	return nil
 }*/
 
-func (u *User) ValidateUser(storage *postgres.Storage, storage1C *mssql.Storage, username, password string) error {
+func (u *User) ValidateUser(ctx context.Context, db postgres.Querier, storage1C *mssql.Storage, username, password string) error {
 	const op = "storage.postgres.entities.user.ValidateUser"
 
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
 	// Проверяем username в БД 1С
-	stmt, err := storage1C.DB.Prepare(qrGetUserFullName)
+	stmt, err := storage1C.DB.PrepareContext(ctx, qrGetUserFullName)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	qrResult, err := stmt.Query(username)
+	qrResult, err := stmt.QueryContext(ctx, username)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -90,7 +221,7 @@ func (u *User) ValidateUser(storage *postgres.Storage, storage1C *mssql.Storage,
 
 	// TO DO: Включить проверку на пароль
 
-	/*qrResult, err := storage.DB.Query(qrGetPassByUsername, username)
+	/*qrResult, err := db.QueryContext(ctx, qrGetPassByUsername, username)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}*/
@@ -111,10 +242,13 @@ func (u *User) ValidateUser(storage *postgres.Storage, storage1C *mssql.Storage,
 	return nil
 }
 
-func (u *User) GetUserID(storage *postgres.Storage, username string) error {
+func (u *User) GetUserID(ctx context.Context, db postgres.Querier, username string) error {
 	const op = "storage.postgres.entities.user.GetUserID"
 
-	qrResult, err := storage.DB.Query(qrGetUserIDByUsername, username)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetUserIDByUsername, username)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -132,10 +266,13 @@ func (u *User) GetUserID(storage *postgres.Storage, username string) error {
 	return nil
 }
 
-func (u *User) GetUsername(storage *postgres.Storage, userID int) error {
+func (u *User) GetUsername(ctx context.Context, db postgres.Querier, userID int) error {
 	const op = "storage.postgres.entities.user.GetUsername"
 
-	err := storage.DB.QueryRow(qrGetUsernameByUserID, userID).Scan(&u.Username)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, qrGetUsernameByUserID, userID).Scan(&u.Username)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -143,27 +280,51 @@ func (u *User) GetUsername(storage *postgres.Storage, userID int) error {
 	return nil
 }
 
-func (u *User) GetImagePath(storage *postgres.Storage, userID int) error {
+func (u *User) GetImagePath(ctx context.Context, db postgres.Querier, userID int) error {
 	const op = "storage.postgres.entities.user.GetImagePath"
 
-	err := storage.DB.QueryRow(qrGetImagePathByUserID, userID).Scan(&u.ImagePath)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, qrGetImagePathByUserID, userID).Scan(&u.ImagePath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReplaceImagePath sets avatar of the user, empty path removes it. The replaced path is set to u.ImagePath
+func (u *User) ReplaceImagePath(ctx context.Context, db postgres.Querier, userID int, imagePath string) error {
+	const op = "storage.postgres.entities.user.ReplaceImagePath"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, qrReplaceImagePath, userID, imagePath).Scan(&u.ImagePath)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storageHandler.ErrUserIDDoesNotExist)
+		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (u *User) GetUserInfo(storage *postgres.Storage, username string) error {
+func (u *User) GetUserInfo(ctx context.Context, db postgres.Querier, username string) error {
 	const op = "storage.postgres.entities.user.GetUserInfo"
 
-	stmt, err := storage.DB.Prepare(qrGetUserInfo)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(ctx, qrGetUserInfo)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	err = stmt.QueryRow(username).Scan(&u.FullName, &u.Position, &u.Department, &u.Mail, &u.Mobile, &u.Chief)
+	err = stmt.QueryRowContext(ctx, username).Scan(&u.FullName, &u.Position, &u.Department, &u.Mail, &u.Mobile, &u.Chief, &u.ChiefID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -171,16 +332,19 @@ func (u *User) GetUserInfo(storage *postgres.Storage, username string) error {
 	return nil
 }
 
-func (u *User) GetAllUsersInfo(storage *postgres.Storage) ([]User, error) {
+func (u *User) GetAllUsersInfo(ctx context.Context, db postgres.Querier) ([]User, error) {
 	const op = "storage.postgres.entities.user.GetAllUsersInfo"
 
-	stmt, err := storage.DB.Prepare(qrGetAllUsersInfo)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(ctx, qrGetAllUsersInfo)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	qrResult, err := stmt.Query()
+	qrResult, err := stmt.QueryContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -197,16 +361,19 @@ func (u *User) GetAllUsersInfo(storage *postgres.Storage) ([]User, error) {
 	return us, nil
 }
 
-func (u *User) GetFullNameByUsername(storage1C *mssql.Storage, username string) error {
+func (u *User) GetFullNameByUsername(ctx context.Context, storage1C *mssql.Storage, username string) error {
 	const op = "storage.postgres.entities.user.GetFullNameByUsername"
 
-	stmt, err := storage1C.DB.Prepare(qrGetUserFullName)
+	ctx, cancel := context.WithTimeout(ctx, postgres.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt, err := storage1C.DB.PrepareContext(ctx, qrGetUserFullName)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	err = stmt.QueryRow(username).Scan(&u.FullName)
+	err = stmt.QueryRowContext(ctx, username).Scan(&u.FullName)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -214,10 +381,13 @@ func (u *User) GetFullNameByUsername(storage1C *mssql.Storage, username string)
 	return nil
 }
 
-func (u *User) GetRoleByUsername(storage *postgres.Storage, username string) error {
+func (u *User) GetRoleByUsername(ctx context.Context, db postgres.Querier, username string) error {
 	const op = "storage.postgres.entities.user.GetRoleByUsername"
 
-	err := storage.DB.QueryRow(qrGetRole, username).Scan(&u.Role)
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	err := db.QueryRowContext(ctx, qrGetRole, username).Scan(&u.Role)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -225,67 +395,177 @@ func (u *User) GetRoleByUsername(storage *postgres.Storage, username string) err
 	return nil
 }
 
-type RefreshToken struct {
-	UserID         int    `json:"user_id,omitempty"`
-	RefreshTokenID string `json:"refresh_token_id,omitempty"`
+type Session struct {
+	SessionID      string    `json:"session_id"`
+	UserID         int       `json:"user_id,omitempty"`
+	DeviceID       string    `json:"-"`
+	TokenID        string    `json:"-"`
+	RefreshTokenID string    `json:"-"`
+	UserAgent      string    `json:"user_agent"`
+	IP             string    `json:"ip"`
+	CreationDate   time.Time `json:"creation_date"`
+	LastUsedDate   time.Time `json:"last_used_date"`
 }
 
-func (r *RefreshToken) ValidateRefreshTokenID(storage *postgres.Storage, username, refreshTokenID string) error {
-	const op = "storage.postgres.entities.user.ValidateRefreshTokenID"
+// NewSession stores the session of the user device. The previous session of the same device is replaced
+func (s *Session) NewSession(ctx context.Context, db postgres.Querier, username, sessionID, deviceID, tokenID, refreshTokenID, userAgent, ip string) error {
+	const op = "storage.postgres.entities.user.NewSession"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
 
-	qrResult1, err := storage.DB.Query(qrGetUserIDByUsername, username)
+	_, err := db.ExecContext(ctx, qrNewSession, sessionID, username, deviceID, tokenID, refreshTokenID, userAgent, ip)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer qrResult1.Close()
 
-	if !qrResult1.Next() {
-		return fmt.Errorf("%s: wrong username", op)
+	return nil
+}
+
+// RotateRefreshTokenID replaces token IDs of the session and returns the access token ID of the session.
+// The previous refresh token ID is accepted during grace after the rotation: it's a parallel refresh,
+// the session is not changed and its current access token ID is returned instead of tokenID.
+// Any other not latest refresh token ID means reuse, so the session (whole token family) is revoked
+func (s *Session) RotateRefreshTokenID(ctx context.Context, db postgres.Querier, username, sessionID, oldRefreshTokenID, tokenID, refreshTokenID string, grace time.Duration) (string, error) {
+	const op = "storage.postgres.entities.user.RotateRefreshTokenID"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, qrRotateRefreshTokenID, sessionID, username, oldRefreshTokenID, tokenID, refreshTokenID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	var userID int
-	if err := qrResult1.Scan(&userID); err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsAffected != 0 {
+		return tokenID, nil
+	}
+
+	// Токен только что заменен параллельным запросом
+	var currentTokenID sql.NullString
+	err = db.QueryRowContext(ctx, qrGetRotatedTokenID, sessionID, username, oldRefreshTokenID, grace.Seconds()).Scan(&currentTokenID)
+	if err == nil {
+		return currentTokenID.String, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	qrResult2, err := storage.DB.Query(qrGetRefreshTokenIDByUserID, userID)
+	// Токен не последний в сессии или сессии нет
+	var revoked int
+	if err := db.QueryRowContext(ctx, qrRevokeSession, sessionID).Scan(&revoked); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked == 0 {
+		return "", fmt.Errorf("%s: %w", op, storageHandler.ErrSessionDoesNotExist)
+	}
+
+	return "", fmt.Errorf("%s: %w", op, storageHandler.ErrRefreshTokenReused)
+}
+
+func (s *Session) GetSessionsByUserID(ctx context.Context, db postgres.Querier, userID int) ([]Session, error) {
+	const op = "storage.postgres.entities.user.GetSessionsByUserID"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	qrResult, err := db.QueryContext(ctx, qrGetSessionsByUserID, userID)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	defer qrResult2.Close()
+	defer qrResult.Close()
 
-	if !qrResult2.Next() {
-		return fmt.Errorf("%s: wrong userID", op)
+	ss := []Session{}
+	for qrResult.Next() {
+		var s Session
+		if err := qrResult.Scan(&s.SessionID, &s.UserAgent, &s.IP, &s.CreationDate, &s.LastUsedDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		s.UserID = userID
+		ss = append(ss, s)
 	}
-	var correctRefreshTokenID string
-	if err := qrResult2.Scan(&correctRefreshTokenID); err != nil {
+
+	return ss, nil
+}
+
+// DeleteSession revokes session only if it belongs to the user
+func (s *Session) DeleteSession(ctx context.Context, db postgres.Querier, userID int, sessionID string) error {
+	const op = "storage.postgres.entities.user.DeleteSession"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var revoked int
+	if err := db.QueryRowContext(ctx, qrRevokeUserSession, sessionID, userID).Scan(&revoked); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-
-	if refreshTokenID != correctRefreshTokenID {
-		return fmt.Errorf("%s: wrong refresh token ID", op)
+	if revoked == 0 {
+		return fmt.Errorf("%s: %w", op, storageHandler.ErrSessionDoesNotExist)
 	}
 
 	return nil
 }
 
-func (r *RefreshToken) StoreRefreshTokenID(storage *postgres.Storage, username, refreshTokenID string) error {
-	const op = "storage.postgres.entities.user.StoreRefreshTokenID"
+// RevokeSession revokes session of the user found by username. Missing session is not an error
+func (s *Session) RevokeSession(ctx context.Context, db postgres.Querier, username, sessionID string) error {
+	const op = "storage.postgres.entities.user.RevokeSession"
 
-	qrResult1, err := storage.DB.Query(qrGetUserIDByUsername, username)
-	if err != nil {
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var revoked int
+	if err := db.QueryRowContext(ctx, qrRevokeUsernameSession, sessionID, username).Scan(&revoked); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer qrResult1.Close()
 
-	if !qrResult1.Next() {
-		return fmt.Errorf("%s: wrong username", op)
+	return nil
+}
+
+// RevokeSessionsByUserID revokes all sessions of the user and returns amount of revoked sessions
+func (s *Session) RevokeSessionsByUserID(ctx context.Context, db postgres.Querier, userID int) (int, error) {
+	const op = "storage.postgres.entities.user.RevokeSessionsByUserID"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var revoked int
+	if err := db.QueryRowContext(ctx, qrRevokeSessionsByUserID, userID).Scan(&revoked); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	var userID int
-	if err := qrResult1.Scan(&userID); err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+
+	return revoked, nil
+}
+
+type RevokedToken struct {
+	TokenID        string    `json:"token_id"`
+	RevocationDate time.Time `json:"revocation_date"`
+}
+
+func (rt *RevokedToken) IsTokenRevoked(ctx context.Context, db postgres.Querier, tokenID string) (bool, error) {
+	const op = "storage.postgres.entities.user.IsTokenRevoked"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	var isRevoked bool
+	if err := db.QueryRowContext(ctx, qrIsTokenRevoked, tokenID).Scan(&isRevoked); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = storage.DB.Exec(qrStoreRefreshTokenID, userID, refreshTokenID)
+	return isRevoked, nil
+}
+
+// DeleteOldRevokedTokens deletes tokens revoked before the date. Such tokens are already expired
+func (rt *RevokedToken) DeleteOldRevokedTokens(ctx context.Context, db postgres.Querier, before time.Time) error {
+	const op = "storage.postgres.entities.user.DeleteOldRevokedTokens"
+
+	ctx, cancel := postgres.WithQueryTimeout(ctx, db)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, qrDeleteOldRevokedTokens, before)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
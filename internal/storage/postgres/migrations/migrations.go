@@ -0,0 +1,233 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"portal/internal/storage/postgres"
+)
+
+// Файлы миграций: <версия>_<имя>.up.sql и <версия>_<имя>.down.sql
+//
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileNameRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var ErrNoAppliedMigrations = errors.New("no applied migrations")
+
+const (
+	qrCreateMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations(
+								   version INT PRIMARY KEY,
+								   "name" varchar(100) NOT NULL,
+								   applied_at timestamp NOT NULL
+							   );`
+	// Блокировка на время транзакции, чтобы два экземпляра не применяли миграции одновременно
+	qrLock                 = `SELECT pg_advisory_xact_lock(hashtext('schema_migrations'));`
+	qrGetAppliedMigrations = `SELECT version, applied_at FROM schema_migrations ORDER BY version;`
+	qrIsApplied            = `SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1);`
+	qrGetLastMigration     = `SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1;`
+	qrNewMigration         = `INSERT INTO schema_migrations(version, "name", applied_at) VALUES ($1, $2, CURRENT_TIMESTAMP);`
+	qrDeleteMigration      = `DELETE FROM schema_migrations WHERE version = $1;`
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// Load returns embedded migrations ordered by version. Each migration must have both up and down files
+func Load() ([]Migration, error) {
+	const op = "storage.postgres.migrations.Load"
+
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileNameRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s: invalid migration file name %q", op, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+
+		data, err := files.ReadFile(path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%s: migration %d has different names %q and %q", op, version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	ms := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("%s: migration %d_%s must have up and down files", op, m.Version, m.Name)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+
+	return ms, nil
+}
+
+// Up applies all pending migrations. Each migration runs in its own transaction together with
+// the schema_migrations record. Returns applied migrations
+func Up(ctx context.Context, storage *postgres.Storage) ([]Migration, error) {
+	const op = "storage.postgres.migrations.Up"
+
+	ms, err := Load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := storage.DB.ExecContext(ctx, qrCreateMigrationsTable); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	applied := []Migration{}
+	for _, m := range ms {
+		var done bool
+		err := storage.WithTx(ctx, func(tx postgres.Querier) error {
+			if _, err := tx.ExecContext(ctx, qrLock); err != nil {
+				return err
+			}
+			// Проверяем после блокировки: миграцию мог применить другой экземпляр
+			if err := tx.QueryRowContext(ctx, qrIsApplied, m.Version).Scan(&done); err != nil || done {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, qrNewMigration, m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("%s: migration %d_%s: %w", op, m.Version, m.Name, err)
+		}
+		if !done {
+			applied = append(applied, m)
+		}
+	}
+
+	return applied, nil
+}
+
+// Down rolls back the last applied migration
+func Down(ctx context.Context, storage *postgres.Storage) (Migration, error) {
+	const op = "storage.postgres.migrations.Down"
+
+	ms, err := Load()
+	if err != nil {
+		return Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := storage.DB.ExecContext(ctx, qrCreateMigrationsTable); err != nil {
+		return Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var m Migration
+	err = storage.WithTx(ctx, func(tx postgres.Querier) error {
+		if _, err := tx.ExecContext(ctx, qrLock); err != nil {
+			return err
+		}
+
+		var version int
+		if err := tx.QueryRowContext(ctx, qrGetLastMigration).Scan(&version); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoAppliedMigrations
+			}
+			return err
+		}
+
+		i := sort.Search(len(ms), func(i int) bool { return ms[i].Version >= version })
+		if i == len(ms) || ms[i].Version != version {
+			return fmt.Errorf("applied migration %d is unknown to this build", version)
+		}
+		m = ms[i]
+
+		if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, qrDeleteMigration, m.Version)
+		return err
+	})
+	if err != nil {
+		return Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return m, nil
+}
+
+// GetStatus returns all known migrations, AppliedAt is nil for pending ones
+func GetStatus(ctx context.Context, storage *postgres.Storage) ([]Status, error) {
+	const op = "storage.postgres.migrations.GetStatus"
+
+	ms, err := Load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := storage.DB.ExecContext(ctx, qrCreateMigrationsTable); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	qrResult, err := storage.DB.QueryContext(ctx, qrGetAppliedMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	appliedAt := map[int]time.Time{}
+	for qrResult.Next() {
+		var version int
+		var t time.Time
+		if err := qrResult.Scan(&version, &t); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		appliedAt[version] = t
+	}
+	if err := qrResult.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	statuses := make([]Status, 0, len(ms))
+	for _, m := range ms {
+		s := Status{Version: m.Version, Name: m.Name}
+		if t, ok := appliedAt[m.Version]; ok {
+			s.AppliedAt = &t
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	ms, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(ms) == 0 {
+		t.Fatal("Load() returned no migrations")
+	}
+
+	// Версии идут по порядку без пропусков, начиная с 1
+	for i, m := range ms {
+		if m.Version != i+1 {
+			t.Errorf("migration #%d has version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Name == "" {
+			t.Errorf("migration %d has empty name", m.Version)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d_%s has empty up or down", m.Version, m.Name)
+		}
+	}
+}
+
+func TestFileNameRe(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		match   bool
+		version string
+		mName   string
+		dir     string
+	}{
+		{name: "up", file: "0001_init.up.sql", match: true, version: "0001", mName: "init", dir: "up"},
+		{name: "down", file: "0012_session_device.down.sql", match: true, version: "0012", mName: "session_device", dir: "down"},
+		{name: "no direction", file: "0001_init.sql", match: false},
+		{name: "no version", file: "init.up.sql", match: false},
+		{name: "wrong direction", file: "0001_init.redo.sql", match: false},
+		{name: "wrong extension", file: "0001_init.up.txt", match: false},
+		{name: "dash in name", file: "0001_add-index.up.sql", match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := fileNameRe.FindStringSubmatch(tt.file)
+			if (match != nil) != tt.match {
+				t.Fatalf("match %q = %v, want %v", tt.file, match != nil, tt.match)
+			}
+			if !tt.match {
+				return
+			}
+			if match[1] != tt.version || match[2] != tt.mName || match[3] != tt.dir {
+				t.Errorf("match %q = %v, want [%s %s %s]", tt.file, match[1:], tt.version, tt.mName, tt.dir)
+			}
+		})
+	}
+}
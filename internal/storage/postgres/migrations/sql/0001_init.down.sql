@@ -0,0 +1,22 @@
+DROP VIEW IF EXISTS post_tags;
+DROP VIEW IF EXISTS likes_amount;
+DROP TABLE IF EXISTS comment;
+DROP TABLE IF EXISTS "like";
+DROP TABLE IF EXISTS in_post_tag;
+DROP TABLE IF EXISTS tag;
+DROP TABLE IF EXISTS post_image;
+DROP TABLE IF EXISTS post;
+DROP VIEW IF EXISTS locker_and_locker_reservation;
+DROP TABLE IF EXISTS locker_reservation;
+DROP TABLE IF EXISTS locker;
+DROP VIEW IF EXISTS place_and_reservation;
+DROP TABLE IF EXISTS reservation;
+DROP TABLE IF EXISTS place;
+DROP TABLE IF EXISTS revoked_token;
+DROP TABLE IF EXISTS api_client;
+DROP TABLE IF EXISTS "session";
+DROP VIEW IF EXISTS in_active_cart_item;
+DROP TABLE IF EXISTS in_cart_item;
+DROP TABLE IF EXISTS cart;
+DROP TABLE IF EXISTS item;
+DROP TABLE IF EXISTS "user";
@@ -1,4 +1,5 @@
-CREATE TABLE "user"(
+-- Базовая схема. IF NOT EXISTS позволяет применить миграцию к базам, созданным по SQL/templates.txt
+CREATE TABLE IF NOT EXISTS "user"(
 	user_id SERIAL PRIMARY KEY,
 	full_name varchar(150),
 	position varchar(150),
@@ -13,7 +14,7 @@ CREATE TABLE "user"(
 	image_path text
 );
 
-CREATE TABLE item(
+CREATE TABLE IF NOT EXISTS item(
 	item_id SERIAL PRIMARY KEY,
 	"name" varchar(150) NOT NULL,
 	description varchar(500),
@@ -21,34 +22,55 @@ CREATE TABLE item(
 	is_available BOOL NOT NULL
 );
 
-CREATE TABLE in_cart_item(
-	in_cart_item_id SERIAL PRIMARY KEY,
-	cart_id INT REFERENCES cart(cart_id) ON DELETE CASCADE,
-	item_id INT REFERENCES item(item_id) ON DELETE CASCADE,
-	quantity INT NOT NULL);
-
-ALTER TABLE in_cart_item ADD CONSTRAINT unique_constraint UNIQUE (item_id, cart_id);
-
-CREATE TABLE cart(
+CREATE TABLE IF NOT EXISTS cart(
 	cart_id SERIAL PRIMARY KEY,
 	user_id INT REFERENCES "user"(user_id) ON DELETE CASCADE,
-	is_active BOOL, 
+	is_active BOOL,
 	"date" DATE
 );
 
-CREATE TABLE refresh_token(
-	user_id INT UNIQUE REFERENCES "user"(user_id) ON DELETE CASCADE,
-	refresh_token_id varchar(50) UNIQUE
+CREATE TABLE IF NOT EXISTS in_cart_item(
+	in_cart_item_id SERIAL PRIMARY KEY,
+	cart_id INT REFERENCES cart(cart_id) ON DELETE CASCADE,
+	item_id INT REFERENCES item(item_id) ON DELETE CASCADE,
+	quantity INT NOT NULL,
+	CONSTRAINT unique_constraint UNIQUE (item_id, cart_id)
 );
 
-CREATE VIEW in_active_cart_item AS
+CREATE OR REPLACE VIEW in_active_cart_item AS
 	(SELECT in_cart_item_id, in_cart_item.cart_id, item_id, quantity
 	FROM in_cart_item
 	JOIN cart ON in_cart_item.cart_id=cart.cart_id
 	WHERE cart.is_active = true
 );
 
-CREATE TABLE place(
+CREATE TABLE IF NOT EXISTS "session"(
+	session_id varchar(50) PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES "user"(user_id) ON DELETE CASCADE,
+	token_id varchar(50),
+	refresh_token_id varchar(50) UNIQUE NOT NULL,
+	user_agent text NOT NULL,
+	ip varchar(64),
+	creation_date timestamp NOT NULL,
+	last_used_date timestamp NOT NULL,
+	UNIQUE (user_id, user_agent)
+);
+
+CREATE TABLE IF NOT EXISTS api_client(
+	client_id varchar(64) PRIMARY KEY,
+	"name" varchar(150) NOT NULL,
+	secret_hash text NOT NULL,
+	scopes INT[] NOT NULL,
+	is_active bool NOT NULL,
+	creation_date timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS revoked_token(
+	token_id varchar(50) PRIMARY KEY,
+	revocation_date timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS place(
 	place_id SERIAL PRIMARY KEY,
 	name varchar(64),
 	phone varchar(32) UNIQUE,
@@ -56,7 +78,7 @@ CREATE TABLE place(
 	second_screen bool
 );
 
-CREATE TABLE reservation(
+CREATE TABLE IF NOT EXISTS reservation(
 	reservation_id SERIAL PRIMARY KEY,
 	place_id int REFERENCES place(place_id) ON DELETE CASCADE,
 	"start" timestamp,
@@ -64,18 +86,18 @@ CREATE TABLE reservation(
 	user_id int REFERENCES "user"(user_id) ON DELETE CASCADE
 );
 
-CREATE VIEW place_and_reservation AS (
+CREATE OR REPLACE VIEW place_and_reservation AS (
 	SELECT reservation.place_id, place.name, place.phone, reservation.start, reservation.finish, reservation.user_id
- 	FROM reservation
- 	JOIN place ON reservation.place_id = place.place_id
+	FROM reservation
+	JOIN place ON reservation.place_id = place.place_id
 );
 
-CREATE TABLE locker(
+CREATE TABLE IF NOT EXISTS locker(
 	locker_id SERIAL PRIMARY KEY,
 	name varchar(64)
 );
 
-CREATE TABLE locker_reservation(
+CREATE TABLE IF NOT EXISTS locker_reservation(
 	locker_reservation_id SERIAL PRIMARY KEY,
 	locker_id int REFERENCES locker(locker_id) ON DELETE CASCADE,
 	"start" timestamp,
@@ -83,13 +105,13 @@ CREATE TABLE locker_reservation(
 	user_id int REFERENCES "user"(user_id) ON DELETE CASCADE
 );
 
-CREATE VIEW locker_and_locker_reservation AS (
+CREATE OR REPLACE VIEW locker_and_locker_reservation AS (
 	SELECT locker_reservation.locker_id, locker.name, locker_reservation.start, locker_reservation.finish, locker_reservation.user_id
- 	FROM locker_reservation
- 	JOIN locker ON locker_reservation.locker_id = locker.locker_id
+	FROM locker_reservation
+	JOIN locker ON locker_reservation.locker_id = locker.locker_id
 );
 
-CREATE TABLE post(
+CREATE TABLE IF NOT EXISTS post(
 	post_id SERIAL PRIMARY KEY,
 	title varchar(256),
 	"text" text,
@@ -97,33 +119,35 @@ CREATE TABLE post(
 	update_date timestamp
 );
 
-CREATE TABLE post_image(
+-- Просмотры поста, в SQL/templates.txt отсутствовали
+ALTER TABLE post ADD COLUMN IF NOT EXISTS views INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS post_image(
 	post_image_id SERIAL PRIMARY KEY,
 	post_id INT REFERENCES post(post_id) ON DELETE CASCADE,
 	"path" TEXT NOT NULL
 );
 
-CREATE TABLE tag(
+CREATE TABLE IF NOT EXISTS tag(
 	tag_id SERIAL PRIMARY KEY,
 	"name" varchar(50) UNIQUE NOT NULL,
 	background_color varchar(16) UNIQUE NOT NULL,
 	text_color text
 );
 
-CREATE TABLE in_post_tag(
+CREATE TABLE IF NOT EXISTS in_post_tag(
 	in_post_tag_id SERIAL PRIMARY KEY,
 	tag_id INT REFERENCES tag(tag_id) ON DELETE CASCADE,
 	post_id INT REFERENCES post(post_id) ON DELETE CASCADE
 );
 
-CREATE TABLE "like"(
+CREATE TABLE IF NOT EXISTS "like"(
 	user_id INT REFERENCES "user"(user_id) ON DELETE CASCADE,
-	post_id INT REFERENCES post(post_id) ON DELETE CASCADE
+	post_id INT REFERENCES post(post_id) ON DELETE CASCADE,
+	CONSTRAINT like_constraint UNIQUE (user_id, post_id)
 );
 
-ALTER TABLE "like" ADD CONSTRAINT like_constraint UNIQUE (user_id, post_id);
-
-CREATE TABLE comment(
+CREATE TABLE IF NOT EXISTS comment(
 	comment_id SERIAL PRIMARY KEY,
 	user_id INT REFERENCES "user"(user_id) ON DELETE CASCADE,
 	post_id INT REFERENCES post(post_id) ON DELETE CASCADE,
@@ -133,14 +157,14 @@ CREATE TABLE comment(
 	is_checked boolean NOT NULL
 );
 
-CREATE VIEW likes_amount AS (
+CREATE OR REPLACE VIEW likes_amount AS (
 	SELECT post_id, COUNT(user_id) AS likes_amount
 	FROM "like"
 	GROUP BY post_id
 );
 
-CREATE VIEW post_tags AS (
+CREATE OR REPLACE VIEW post_tags AS (
 	SELECT in_post_tag.post_id, tag.*
 	FROM in_post_tag
 	JOIN tag ON in_post_tag.tag_id = tag.tag_id
-);
\ No newline at end of file
+);
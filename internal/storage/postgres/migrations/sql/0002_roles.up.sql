@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS "role"(
+	role_id INT PRIMARY KEY,
+	"name" varchar(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS role_permission(
+	role_id INT REFERENCES "role"(role_id) ON DELETE CASCADE,
+	permission varchar(100) NOT NULL,
+	PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_role(
+	user_id INT REFERENCES "user"(user_id) ON DELETE CASCADE,
+	role_id INT REFERENCES "role"(role_id) ON DELETE CASCADE,
+	PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO "role" (role_id, "name") VALUES
+	(1, 'SuperAdmin'),
+	(2, 'ShopEditor'),
+	(3, 'NewsEditor'),
+	(4, 'ReservationEditor'),
+	(49, 'UserWithOutReservation'),
+	(50, 'User')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission) VALUES
+	(1, 'news.write'), (1, 'comment.moderate'), (1, 'shop.manage'), (1, 'reservation.create'), (1, 'reservation.admin'),
+	(1, 'session.admin'), (1, 'client.manage'), (1, 'role.manage'),
+	(2, 'shop.manage'), (2, 'reservation.create'),
+	(3, 'news.write'), (3, 'comment.moderate'), (3, 'reservation.create'),
+	(4, 'reservation.admin'), (4, 'reservation.create'),
+	(50, 'reservation.create')
+ON CONFLICT DO NOTHING;
+
+-- Роль из "user"."role" остается основной (попадает в токен), в user_role хранятся только дополнительные роли.
+-- Основная роль в user_role не копируется: иначе после смены основной роли права старой остались бы у пользователя
+
+CREATE TABLE IF NOT EXISTS role_audit(
+	audit_id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES "user"(user_id) ON DELETE CASCADE,
+	target varchar(10) NOT NULL,
+	old_roles INT[] NOT NULL,
+	new_roles INT[] NOT NULL,
+	changed_by varchar(50) NOT NULL,
+	"source" varchar(10) NOT NULL,
+	creation_date timestamp NOT NULL
+);
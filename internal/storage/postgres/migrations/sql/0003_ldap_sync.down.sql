@@ -0,0 +1,3 @@
+DELETE FROM role_permission WHERE permission = 'user.sync';
+
+ALTER TABLE "user" DROP COLUMN IF EXISTS is_active;
@@ -0,0 +1,4 @@
+-- Учетные записи, отключенные или удаленные в AD, помечаются неактивными при синхронизации
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS is_active BOOL NOT NULL DEFAULT TRUE;
+
+INSERT INTO role_permission (role_id, permission) VALUES (1, 'user.sync') ON CONFLICT DO NOTHING;
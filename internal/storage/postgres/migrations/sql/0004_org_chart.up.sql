@@ -0,0 +1,2 @@
+-- Руководитель из атрибута manager в AD, заполняется синхронизацией с каталогом
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS chief_id INT REFERENCES "user"(user_id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_department_idx;
+DROP INDEX IF EXISTS user_search_trgm_idx;
@@ -0,0 +1,7 @@
+-- Поиск по справочнику: выражение должно совпадать с phoneBookSearchExpr
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS user_search_trgm_idx ON "user" USING gin ((lower(COALESCE(full_name, '') || ' ' || COALESCE(position, '') || ' ' || COALESCE(department, '') || ' ' ||
+	COALESCE(mobile, '') || ' ' || regexp_replace(COALESCE(mobile, ''), '\D', '', 'g'))) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS user_department_idx ON "user" (department);
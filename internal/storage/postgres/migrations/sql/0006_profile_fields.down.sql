@@ -0,0 +1,10 @@
+DELETE FROM role_permission WHERE permission = 'user.manage';
+
+ALTER TABLE "user"
+	DROP COLUMN IF EXISTS overridden_fields,
+	DROP COLUMN IF EXISTS skills,
+	DROP COLUMN IF EXISTS show_birthday,
+	DROP COLUMN IF EXISTS birthday,
+	DROP COLUMN IF EXISTS bio,
+	DROP COLUMN IF EXISTS office,
+	DROP COLUMN IF EXISTS extra_phone;
@@ -0,0 +1,13 @@
+-- Поля профиля, которые пользователь заполняет на портале
+ALTER TABLE "user"
+	ADD COLUMN IF NOT EXISTS extra_phone varchar(25),
+	ADD COLUMN IF NOT EXISTS office varchar(50),
+	ADD COLUMN IF NOT EXISTS bio varchar(500),
+	ADD COLUMN IF NOT EXISTS birthday date,
+	ADD COLUMN IF NOT EXISTS show_birthday BOOL NOT NULL DEFAULT FALSE,
+	ADD COLUMN IF NOT EXISTS skills text[] NOT NULL DEFAULT '{}';
+
+-- Поля из каталога, исправленные администратором. Синхронизация с AD их не перезаписывает
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS overridden_fields text[] NOT NULL DEFAULT '{}';
+
+INSERT INTO role_permission (role_id, permission) VALUES (1, 'user.manage') ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS post_image_post_id_idx;
+
+ALTER TABLE post_image
+	DROP COLUMN IF EXISTS object_name,
+	DROP COLUMN IF EXISTS alt_text,
+	DROP COLUMN IF EXISTS position;
@@ -0,0 +1,9 @@
+-- Порядок и подписи изображений поста. object_name - имя объекта в MinIO для удаления отдельных фото
+ALTER TABLE post_image
+	ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS alt_text varchar(256),
+	ADD COLUMN IF NOT EXISTS object_name text;
+
+UPDATE post_image SET object_name = substring("path" from 'name=(.*)$') WHERE object_name IS NULL;
+
+CREATE INDEX IF NOT EXISTS post_image_post_id_idx ON post_image (post_id, position);
@@ -0,0 +1,2 @@
+-- Очистка хранилища изображений от файлов без ссылок в БД
+INSERT INTO role_permission (role_id, permission) VALUES (1, 'storage.manage') ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS site_counter_daily;
+DROP TABLE IF EXISTS site_counter;
@@ -0,0 +1,18 @@
+-- Счетчики портала. Заменяют параметр portaldb.total_views, который требовал ALTER SYSTEM
+CREATE TABLE IF NOT EXISTS site_counter(
+	"name" varchar(50) PRIMARY KEY,
+	n BIGINT NOT NULL DEFAULT 0
+);
+
+-- История счетчиков по дням для графиков
+CREATE TABLE IF NOT EXISTS site_counter_daily(
+	"name" varchar(50) NOT NULL,
+	"day" date NOT NULL,
+	n BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY ("name", "day")
+);
+
+-- Переносим накопленное значение, если параметр был задан
+INSERT INTO site_counter ("name", n)
+	VALUES ('total_views', COALESCE(NULLIF(current_setting('portaldb.total_views', true), ''), '0')::BIGINT)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+-- Удаленные записи были ошибочными и не восстанавливаются
+SELECT 1;
@@ -0,0 +1,9 @@
+-- Ранее основная роль копировалась в user_role как дополнительная, и права старой основной роли
+-- оставались у пользователя после ее смены. Удаляем такие записи:
+-- копию текущей основной роли
+DELETE FROM user_role ur USING "user" u WHERE ur.user_id = u.user_id AND ur.role_id = u."role";
+
+-- и прежние основные роли, которые администратор не назначал дополнительными
+DELETE FROM user_role ur
+WHERE EXISTS (SELECT 1 FROM role_audit a WHERE a.user_id = ur.user_id AND a.target = 'role' AND ur.role_id = ANY(a.old_roles))
+	AND NOT EXISTS (SELECT 1 FROM role_audit a WHERE a.user_id = ur.user_id AND a.target = 'roles' AND ur.role_id = ANY(a.new_roles));
@@ -0,0 +1,7 @@
+-- Права, запрещенные пользователю группами AD (например, ReservationDeny).
+-- Запрет действует поверх прав всех ролей пользователя, основной и дополнительных
+CREATE TABLE IF NOT EXISTS user_permission_deny(
+	user_id INT REFERENCES "user"(user_id) ON DELETE CASCADE,
+	permission varchar(100) NOT NULL,
+	PRIMARY KEY (user_id, permission)
+);
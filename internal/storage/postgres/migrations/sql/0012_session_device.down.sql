@@ -0,0 +1,10 @@
+ALTER TABLE "session" DROP COLUMN IF EXISTS rotation_date;
+ALTER TABLE "session" DROP COLUMN IF EXISTS prev_refresh_token_id;
+
+-- Из нескольких сессий одного браузера остается последняя использованная
+DELETE FROM "session" s USING "session" newer
+WHERE s.user_id = newer.user_id AND s.user_agent = newer.user_agent
+AND (s.last_used_date, s.session_id) < (newer.last_used_date, newer.session_id);
+DROP INDEX IF EXISTS session_user_id_device_id_key;
+ALTER TABLE "session" DROP COLUMN IF EXISTS device_id;
+ALTER TABLE "session" ADD CONSTRAINT session_user_id_user_agent_key UNIQUE (user_id, user_agent);
@@ -0,0 +1,13 @@
+-- Сессия уникальна для пары пользователь/устройство. Устройство определяется сгенерированным
+-- идентификатором (cookie device_id), а не User-Agent: одинаковые браузеры на разных машинах
+-- не должны заменять сессии друг друга. Существующие сессии считаются отдельными устройствами
+ALTER TABLE "session" ADD COLUMN IF NOT EXISTS device_id varchar(50);
+UPDATE "session" SET device_id = session_id WHERE device_id IS NULL;
+ALTER TABLE "session" ALTER COLUMN device_id SET NOT NULL;
+ALTER TABLE "session" DROP CONSTRAINT IF EXISTS session_user_id_user_agent_key;
+CREATE UNIQUE INDEX IF NOT EXISTS session_user_id_device_id_key ON "session"(user_id, device_id);
+
+-- Предыдущий refresh токен сессии принимается в течение короткого времени после ротации,
+-- чтобы параллельные запросы с одним истекшим access токеном не считались повторным использованием
+ALTER TABLE "session" ADD COLUMN IF NOT EXISTS prev_refresh_token_id varchar(50);
+ALTER TABLE "session" ADD COLUMN IF NOT EXISTS rotation_date timestamp;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS user_username_lower_key;
@@ -0,0 +1,4 @@
+-- Имена пользователей сравниваются без учета регистра: в каталоге и при входе регистр может отличаться.
+-- Если создание индекса не удалось, в таблице есть пользователи, различающиеся только регистром имени,
+-- их нужно объединить вручную до применения миграции
+CREATE UNIQUE INDEX IF NOT EXISTS user_username_lower_key ON "user"(lower(username));
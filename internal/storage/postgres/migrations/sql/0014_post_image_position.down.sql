@@ -0,0 +1 @@
+ALTER TABLE post_image DROP CONSTRAINT IF EXISTS post_image_post_id_position_key;
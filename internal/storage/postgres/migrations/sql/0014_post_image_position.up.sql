@@ -0,0 +1,14 @@
+-- До 0007 у изображений не было порядка, и всем существующим фото поста досталась позиция 0.
+-- Позиции пересчитываются подряд с 0 с сохранением текущего порядка выдачи
+UPDATE post_image pi
+SET position = numbered.position
+FROM (
+	SELECT post_image_id, row_number() OVER (PARTITION BY post_id ORDER BY position, post_image_id) - 1 AS position
+	FROM post_image
+) numbered
+WHERE pi.post_image_id = numbered.post_image_id AND pi.position <> numbered.position;
+
+-- Проверка откладывается до конца транзакции: при перемещении фото позиции временно совпадают
+ALTER TABLE post_image
+	DROP CONSTRAINT IF EXISTS post_image_post_id_position_key,
+	ADD CONSTRAINT post_image_post_id_position_key UNIQUE (post_id, position) DEFERRABLE INITIALLY DEFERRED;
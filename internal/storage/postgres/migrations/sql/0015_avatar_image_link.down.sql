@@ -0,0 +1,3 @@
+UPDATE "user"
+SET image_path = substring(image_path from 'name=(.*)$')
+WHERE image_path LIKE 'https://corp-portal.kama-diesel.ru/api/image?name=avatars/%';
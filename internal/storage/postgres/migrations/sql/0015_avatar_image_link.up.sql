@@ -0,0 +1,4 @@
+-- image_path пользователя хранит ссылку на /api/image, как post_image.path, а не имя объекта
+UPDATE "user"
+SET image_path = 'https://corp-portal.kama-diesel.ru/api/image?name=' || image_path
+WHERE image_path LIKE 'avatars/%';
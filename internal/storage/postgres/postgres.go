@@ -1,68 +1,99 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
+	"time"
 
 	"portal/internal/config"
 
 	_ "github.com/lib/pq"
 )
 
-const (
-	qrGetViews              = `SHOW portaldb.total_views;`
-	qrTemplateIncreaseViews = `ALTER SYSTEM SET portaldb.total_views = `
-	qrReloadConf            = `SELECT pg_reload_conf();`
-)
-
 type Storage struct {
-	DB *sql.DB
+	DB *DB
+}
+
+// DB is the connection pool with the query timeout of the storage
+type DB struct {
+	*sql.DB
+	queryTimeout time.Duration
+}
+
+func (db *DB) QueryTimeout() time.Duration {
+	return db.queryTimeout
+}
+
+// Tx is the transaction opened by WithTx, it has the query timeout of the storage
+type Tx struct {
+	*sql.Tx
+	queryTimeout time.Duration
+}
+
+func (tx *Tx) QueryTimeout() time.Duration {
+	return tx.queryTimeout
+}
+
+// Querier is implemented by both *DB and *Tx. Entity methods take it, so they can run
+// either as separate statements or as a part of the transaction opened by WithTx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryTimeout() time.Duration
+}
+
+// DefaultQueryTimeout is used when SQL.QueryTimeout is not set
+const DefaultQueryTimeout = 10 * time.Second
+
+// WithQueryTimeout returns ctx with the query deadline of db. Entity methods call it before querying,
+// so a slow query is cancelled even if the request context has no deadline
+func WithQueryTimeout(ctx context.Context, db Querier) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, db.QueryTimeout())
+}
+
+// WithTxTimeout returns ctx with the deadline of the whole transaction: entity methods opening
+// a transaction by WithTx use it, so all statements together must finish within the query timeout
+func WithTxTimeout(ctx context.Context, storage *Storage) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, storage.DB.QueryTimeout())
 }
 
 func New(cfg config.SQL) (*Storage, error) {
 	const op = "storage.postgres.New" // Имя текущей функции для логов и ошибок
 
-	DB, err := sql.Open(cfg.PostgresDriver, cfg.PostgresInfo) // Подключаемся к БД
+	db, err := sql.Open(cfg.PostgresDriver, cfg.PostgresInfo) // Подключаемся к БД
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{DB: DB}, nil
+	queryTimeout := cfg.QueryTimeout
+	if queryTimeout <= 0 {
+		queryTimeout = DefaultQueryTimeout
+	}
+
+	return &Storage{DB: &DB{DB: db, queryTimeout: queryTimeout}}, nil
 }
 
-func (s *Storage) IncreaseViews(amount int) error {
-	const op = "storage.postgres.IncreaseViews"
+// WithTx runs fn in a transaction. The transaction is committed if fn returns nil and rolled back otherwise
+func (s *Storage) WithTx(ctx context.Context, fn func(tx Querier) error) error {
+	const op = "storage.postgres.WithTx"
 
-	var views int
-	err := s.DB.QueryRow(qrGetViews).Scan(&views)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback()
 
-	totalViews := views + amount
-	qrIncreaseViews := qrTemplateIncreaseViews + strconv.Itoa(totalViews) + ";"
-	_, err = s.DB.Exec(qrIncreaseViews)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if err := fn(&Tx{Tx: tx, queryTimeout: s.DB.queryTimeout}); err != nil {
+		return err
 	}
 
-	_, err = s.DB.Exec(qrReloadConf)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
-
-func (s *Storage) GetViews() (int, error) {
-	const op = "storage.postgres.IncreaseViews"
-
-	var views int
-	err := s.DB.QueryRow(qrGetViews).Scan(&views)
-	if err != nil {
-		return -1, fmt.Errorf("%s: %w", op, err)
-	}
-
-	return views, nil
-}
@@ -3,7 +3,16 @@ package storageHandler
 import "errors"
 
 var (
-	ErrCartDoesNotExist   = errors.New("cart does not exist")
-	ErrUserIDDoesNotExist = errors.New("user id doesn not exist")
-	ErrPageInOutOfRange   = errors.New("page in out of range")
+	ErrCartDoesNotExist      = errors.New("cart does not exist")
+	ErrItemDoesNotExist      = errors.New("item does not exist")
+	ErrUserIDDoesNotExist    = errors.New("user id doesn not exist")
+	ErrPageInOutOfRange      = errors.New("page in out of range")
+	ErrSessionDoesNotExist   = errors.New("session does not exist")
+	ErrRefreshTokenReused    = errors.New("refresh token reused")
+	ErrClientDoesNotExist    = errors.New("api client does not exist")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrInvalidSortField      = errors.New("invalid sort field")
+	ErrPostDoesNotExist      = errors.New("post does not exist")
+	ErrPostImageDoesNotExist = errors.New("post image does not exist")
+	ErrImageDoesNotExist     = errors.New("image does not exist")
 )
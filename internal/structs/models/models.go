@@ -9,4 +9,6 @@ type Image struct {
 	Name      string
 	Size      int64
 	Extension string
+	// Если не указан, определяется по Extension
+	ContentType string
 }
@@ -0,0 +1,16 @@
+package permissions
+
+// Права доступа. Соответствие ролей и прав хранится в таблице role_permission
+const (
+	NewsWrite         = "news.write"
+	CommentModerate   = "comment.moderate"
+	ShopManage        = "shop.manage"
+	ReservationCreate = "reservation.create"
+	ReservationAdmin  = "reservation.admin"
+	SessionAdmin      = "session.admin"
+	ClientManage      = "client.manage"
+	RoleManage        = "role.manage"
+	UserSync          = "user.sync"
+	UserManage        = "user.manage"
+	StorageManage     = "storage.manage"
+)